@@ -0,0 +1,379 @@
+package main
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const (
+	apiSocketName   = "clyp-api.sock"
+	apiDefaultLimit = 30
+	apiMaxLimit     = 1000
+)
+
+//go:embed resources/openapi.json
+var openAPIDocument []byte
+
+type API struct {
+	mutex       sync.Mutex
+	subscribers map[chan APIEvent]struct{}
+}
+
+type APIEvent struct {
+	Type string     `json:"type"`
+	Item ExportItem `json:"item"`
+}
+
+type APIError struct {
+	Error string `json:"error"`
+}
+
+type APIAddRequest struct {
+	Content string `json:"content"`
+	Copy    bool   `json:"copy"`
+}
+
+var (
+	errAPINotFound = errors.New("not found")
+	errAPIRejected = errors.New("rejected by a hook")
+)
+
+func (api *API) listen() {
+	if !config.APIEnabled {
+		return
+	}
+
+	handler := api.handler()
+
+	if listener, err := listenPrivateSocket(apiSocketPath()); err != nil {
+		log.Printf("Failed to listen on API socket: %v", err)
+		notifier.error("Failed to listen on API socket: %v", err)
+	} else {
+		go http.Serve(listener, handler)
+	}
+
+	if config.APIAddress == "" {
+		return
+	}
+	if config.APIToken == "" {
+		log.Printf("API address %s ignored, api_token is not set", config.APIAddress)
+		return
+	}
+	host, _, err := net.SplitHostPort(config.APIAddress)
+	if ip := net.ParseIP(host); err != nil || (host != "localhost" && (ip == nil || !ip.IsLoopback())) {
+		log.Printf("API address %s ignored, only loopback addresses are allowed", config.APIAddress)
+		return
+	}
+	go func() {
+		if err := http.ListenAndServe(config.APIAddress, api.requireToken(handler)); err != nil {
+			log.Printf("Failed to listen on %s: %v", config.APIAddress, err)
+		}
+	}()
+}
+
+func apiSocketPath() string {
+	return glib.GetUserRuntimeDir() + "/" + apiSocketName
+}
+
+func (api *API) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(openAPIDocument)
+	})
+	mux.HandleFunc("GET /items", api.listItems)
+	mux.HandleFunc("POST /items", api.addItem)
+	mux.HandleFunc("GET /items/{id}", api.getItem)
+	mux.HandleFunc("DELETE /items/{id}", api.deleteItem)
+	mux.HandleFunc("POST /items/{id}/copy", api.copyItem)
+	mux.HandleFunc("PUT /items/{id}/pin", api.pinItem(true))
+	mux.HandleFunc("DELETE /items/{id}/pin", api.pinItem(false))
+	mux.HandleFunc("GET /events", api.streamEvents)
+	return mux
+}
+
+func (api *API) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(config.APIToken)) != 1 {
+			writeAPIError(writer, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+func (api *API) onMainLoop(run func() (any, error)) (any, error) {
+	type result struct {
+		value any
+		err   error
+	}
+	results := make(chan result, 1)
+	glib.IdleAdd(func() {
+		value, err := run()
+		results <- result{value, err}
+	})
+	r := <-results
+	return r.value, r.err
+}
+
+func (api *API) listItems(writer http.ResponseWriter, request *http.Request) {
+	limit := apiDefaultLimit
+	if value := request.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeAPIError(writer, http.StatusBadRequest, fmt.Errorf("invalid limit %q", value))
+			return
+		}
+		limit = min(parsed, apiMaxLimit)
+	}
+
+	query := request.URL.Query().Get("q")
+	if err := validateSearchFilter(query); err != nil {
+		writeAPIError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := api.onMainLoop(func() (any, error) {
+		items, err := clipboard.search(query, limit)
+		if err != nil {
+			return nil, err
+		}
+		return apiItems(items)
+	})
+	writeAPIResult(writer, http.StatusOK, result, err)
+}
+
+func (api *API) getItem(writer http.ResponseWriter, request *http.Request) {
+	result, err := api.onMainLoop(func() (any, error) {
+		item, err := apiFindItem(request.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		return (&Export{format: "json"}).item(item)
+	})
+	writeAPIResult(writer, http.StatusOK, result, err)
+}
+
+func (api *API) addItem(writer http.ResponseWriter, request *http.Request) {
+	var addRequest APIAddRequest
+	if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, 16<<20)).Decode(&addRequest); err != nil {
+		writeAPIError(writer, http.StatusBadRequest, err)
+		return
+	}
+	content := strings.TrimSpace(addRequest.Content)
+	if content == "" {
+		writeAPIError(writer, http.StatusBadRequest, fmt.Errorf("content is empty"))
+		return
+	}
+
+	// Added items are cleaned and passed to the hooks like captured ones, the
+	// hooks run on this goroutine so the response can return the saved item.
+	prepared, _ := api.onMainLoop(func() (any, error) {
+		if config.CleanURLs {
+			content = cleanURL(content)
+		}
+		kind, language := classifyContent(content, 1)
+		return HookEntry{content: content, itemType: 1, kind: kind, language: language}, nil
+	})
+	entry, accepted := hooks.run(prepared.(HookEntry))
+	if !accepted {
+		writeAPIError(writer, http.StatusUnprocessableEntity, errAPIRejected)
+		return
+	}
+
+	result, err := api.onMainLoop(func() (any, error) {
+		item, err := clipboard.insertItem(entry)
+		if err != nil {
+			return nil, err
+		}
+		if addRequest.Copy {
+			clipboard.recentHash = contentHash(entry.content, 1)
+			clipboard.setText(entry.content)
+		}
+		return (&Export{format: "json"}).item(item)
+	})
+	writeAPIResult(writer, http.StatusCreated, result, err)
+}
+
+func (api *API) deleteItem(writer http.ResponseWriter, request *http.Request) {
+	_, err := api.onMainLoop(func() (any, error) {
+		item, err := apiFindItem(request.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		clipboard.deleteItem(item)
+		return nil, nil
+	})
+	writeAPIResult(writer, http.StatusNoContent, nil, err)
+}
+
+func (api *API) copyItem(writer http.ResponseWriter, request *http.Request) {
+	_, err := api.onMainLoop(func() (any, error) {
+		item, err := apiFindItem(request.PathValue("id"))
+		if err != nil {
+			return nil, err
+		}
+		return nil, clipboard.copy(strconv.Itoa(item.id))
+	})
+	writeAPIResult(writer, http.StatusNoContent, nil, err)
+}
+
+func (api *API) pinItem(pinned bool) http.HandlerFunc {
+	eventType := "item_unpinned"
+	if pinned {
+		eventType = "item_pinned"
+	}
+	return func(writer http.ResponseWriter, request *http.Request) {
+		result, err := api.onMainLoop(func() (any, error) {
+			item, err := apiFindItem(request.PathValue("id"))
+			if err != nil {
+				return nil, err
+			}
+			if err := clipboard.setPinned(strconv.Itoa(item.id), pinned); err != nil {
+				return nil, err
+			}
+			item.pinned = pinned
+			ipc.notify()
+			api.publish(eventType, item)
+			return (&Export{format: "json"}).item(item)
+		})
+		writeAPIResult(writer, http.StatusOK, result, err)
+	}
+}
+
+func (api *API) streamEvents(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writeAPIError(writer, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	events := api.subscribe()
+	defer api.unsubscribe(events)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(writer, ": keep-alive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+func (api *API) subscribe() chan APIEvent {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	if api.subscribers == nil {
+		api.subscribers = make(map[chan APIEvent]struct{})
+	}
+	events := make(chan APIEvent, 16)
+	api.subscribers[events] = struct{}{}
+	return events
+}
+
+func (api *API) unsubscribe(events chan APIEvent) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	delete(api.subscribers, events)
+}
+
+func (api *API) publish(eventType string, item ClipboardItem) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	if len(api.subscribers) == 0 {
+		return
+	}
+
+	exportItem, err := (&Export{format: "json"}).item(item)
+	if err != nil {
+		log.Printf("Failed to publish API event: %v", err)
+		return
+	}
+
+	event := APIEvent{Type: eventType, Item: exportItem}
+	for events := range api.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+func apiFindItem(id string) (ClipboardItem, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return ClipboardItem{}, errAPINotFound
+	}
+	item, err := clipboard.item(id)
+	if err != nil {
+		return ClipboardItem{}, errAPINotFound
+	}
+	return item, nil
+}
+
+func apiItems(items []ClipboardItem) ([]ExportItem, error) {
+	export := Export{format: "json"}
+	exportItems := make([]ExportItem, 0, len(items))
+	for _, item := range items {
+		exportItem, err := export.item(item)
+		if err != nil {
+			return nil, err
+		}
+		exportItems = append(exportItems, exportItem)
+	}
+	return exportItems, nil
+}
+
+func writeAPIResult(writer http.ResponseWriter, status int, result any, err error) {
+	switch {
+	case errors.Is(err, errAPINotFound):
+		writeAPIError(writer, http.StatusNotFound, err)
+	case err != nil:
+		writeAPIError(writer, http.StatusInternalServerError, err)
+	case status == http.StatusNoContent:
+		writer.WriteHeader(status)
+	default:
+		writeAPIJSON(writer, status, result)
+	}
+}
+
+func writeAPIError(writer http.ResponseWriter, status int, err error) {
+	writeAPIJSON(writer, status, APIError{Error: err.Error()})
+}
+
+func writeAPIJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(value)
+}
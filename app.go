@@ -2,7 +2,10 @@ package main
 
 import (
 	_ "embed"
+	"log"
 	"os"
+	"os/exec"
+	"strings"
 
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	_ "github.com/mattn/go-sqlite3"
@@ -25,3 +28,16 @@ func (app *Application) setupDataDir() {
 		}
 	}
 }
+
+func (app *Application) spawn(args ...string) {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "clyp"
+	}
+	cmd := exec.Command(executable, args...)
+	if err := cmd.Start(); err != nil {
+		log.Printf("Failed to start %s: %v", strings.Join(append([]string{"clyp"}, args...), " "), err)
+		return
+	}
+	go cmd.Wait()
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (database *Database) path() string {
+	return app.dataDir + "/clyp.db"
+}
+
+func (database *Database) backupDir() string {
+	return app.dataDir + "/backups"
+}
+
+func (database *Database) backups() ([]string, error) {
+	entries, err := os.ReadDir(database.backupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), "clyp-") && strings.HasSuffix(entry.Name(), ".db") {
+			backups = append(backups, filepath.Join(database.backupDir(), entry.Name()))
+		}
+	}
+	slices.Sort(backups)
+	slices.Reverse(backups)
+
+	return backups, nil
+}
+
+func (database *Database) createBackup() (string, error) {
+	backupPath, err := database.writeBackup()
+	if err != nil {
+		return "", err
+	}
+
+	database.rotateBackups()
+
+	return backupPath, nil
+}
+
+func (database *Database) writeBackup() (string, error) {
+	if err := os.MkdirAll(database.backupDir(), 0755); err != nil {
+		return "", err
+	}
+
+	backupPath := filepath.Join(database.backupDir(), "clyp-"+time.Now().Format("20060102-150405.000000")+".db")
+	if err := database.backupTo(backupPath); err != nil {
+		return "", err
+	}
+
+	return backupPath, nil
+}
+
+func (database *Database) backupIfDue() {
+	backups, err := database.backups()
+	if err != nil {
+		log.Printf("Failed to list backups: %v", err)
+		return
+	}
+
+	if len(backups) > 0 {
+		if info, err := os.Stat(backups[0]); err == nil && time.Since(info.ModTime()) < time.Duration(config.BackupIntervalMinutes)*time.Minute {
+			return
+		}
+	}
+
+	if _, err := database.createBackup(); err != nil {
+		log.Printf("Failed to back up database: %v", err)
+		notifier.error("Failed to back up database: %v", err)
+	}
+}
+
+func (database *Database) rotateBackups() {
+	backups, err := database.backups()
+	if err != nil {
+		log.Printf("Failed to list backups: %v", err)
+		return
+	}
+
+	for i := max(config.BackupCount, 1); i < len(backups); i++ {
+		if err := os.Remove(backups[i]); err != nil {
+			log.Printf("Failed to remove old backup: %v", err)
+		}
+	}
+}
+
+func (database *Database) backupTo(path string) error {
+	temporaryPath := path + ".tmp"
+	os.Remove(temporaryPath)
+
+	destination, err := sql.Open("sqlite3_clyp", temporaryPath)
+	if err != nil {
+		return err
+	}
+
+	err = copyDatabase(destination, database.db)
+	destination.Close()
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	return os.Rename(temporaryPath, path)
+}
+
+func (database *Database) restore(path string) error {
+	if err := checkDatabaseIntegrity(path); err != nil {
+		return err
+	}
+
+	// Old backups are rotated after the restore, the oldest one may be the
+	// one being restored.
+	if _, err := database.writeBackup(); err != nil {
+		return fmt.Errorf("failed to back up current database: %v", err)
+	}
+	defer database.rotateBackups()
+
+	source, err := sql.Open("sqlite3_clyp", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if err := copyDatabase(database.db, source); err != nil {
+		return err
+	}
+
+	database.migrate()
+
+	return nil
+}
+
+func copyDatabase(destination, source *sql.DB) error {
+	ctx := context.Background()
+
+	destinationConn, err := destination.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destinationConn.Close()
+
+	sourceConn, err := source.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sourceConn.Close()
+
+	return destinationConn.Raw(func(destinationDriverConn any) error {
+		return sourceConn.Raw(func(sourceDriverConn any) error {
+			backup, err := destinationDriverConn.(*sqlite3.SQLiteConn).Backup("main", sourceDriverConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+func checkDatabaseIntegrity(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3_clyp", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return integrityCheck(db)
+}
+
+func integrityCheck(db *sql.DB) error {
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+	return nil
+}
+
+func (database *Database) recoverFromBackup() error {
+	backups, err := database.backups()
+	if err != nil {
+		return err
+	}
+
+	for _, backupPath := range backups {
+		if err := checkDatabaseIntegrity(backupPath); err != nil {
+			log.Printf("Skipping backup %s: %v", backupPath, err)
+			continue
+		}
+
+		corruptPath := database.path() + ".corrupt-" + time.Now().Format("20060102-150405")
+		if err := os.Rename(database.path(), corruptPath); err != nil {
+			return err
+		}
+		if err := copyFile(backupPath, database.path()); err != nil {
+			return err
+		}
+		log.Printf("Database was corrupt and has been restored from %s, the corrupt file was moved to %s", backupPath, corruptPath)
+		return nil
+	}
+
+	return fmt.Errorf("no valid backup found")
+}
+
+func copyFile(source, destination string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destinationFile, sourceFile); err != nil {
+		destinationFile.Close()
+		return err
+	}
+
+	return destinationFile.Close()
+}
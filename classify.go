@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	kindText   = "text"
+	kindURL    = "url"
+	kindEmail  = "email"
+	kindPath   = "path"
+	kindColor  = "color"
+	kindJSON   = "json"
+	kindCode   = "code"
+	kindPhone  = "phone"
+	kindNumber = "number"
+	kindImage  = "image"
+)
+
+var kindIcons = map[string]string{
+	kindText:   "text-x-generic-symbolic",
+	kindURL:    "web-browser-symbolic",
+	kindEmail:  "mail-unread-symbolic",
+	kindPath:   "folder-symbolic",
+	kindColor:  "applications-graphics-symbolic",
+	kindJSON:   "text-x-script-symbolic",
+	kindCode:   "utilities-terminal-symbolic",
+	kindPhone:  "call-start-symbolic",
+	kindNumber: "accessories-calculator-symbolic",
+	kindImage:  "image-x-generic-symbolic",
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`^(mailto:)?[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`)
+	pathPattern   = regexp.MustCompile(`^(~|\.{1,2})?/[^\x00\n]*$`)
+	colorPattern  = regexp.MustCompile(`^(#([0-9A-Fa-f]{3,4}|[0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})|(rgba?|hsla?)\(\s*[0-9.]+%?\s*,?\s*[0-9.]+%?\s*,?\s*[0-9.]+%?\s*([,/]\s*[0-9.]+%?\s*)?\))$`)
+	phonePattern  = regexp.MustCompile(`^\+?[0-9][0-9 ().\-]{5,}[0-9]$`)
+	datePattern   = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	numberPattern = regexp.MustCompile(`^[-+]?([0-9]{1,3}([,_ ][0-9]{3})+|[0-9]+)([.,][0-9]+)?([eE][-+]?[0-9]+)?$|^0[xX][0-9A-Fa-f]+$`)
+)
+
+type languageRule struct {
+	language string
+	patterns []*regexp.Regexp
+}
+
+var languageRules = []languageRule{
+	{"go", compilePatterns(`(?m)^package \w+$`, `\bfunc (\(\w+ \*?\w+\) )?\w+\(`, `:= `, `\bif err != nil\b`, `(?m)^import \($`)},
+	{"python", compilePatterns(`(?m)^\s*def \w+\(.*\):$`, `(?m)^\s*(from \w+(\.\w+)* )?import \w+`, `\bself\.`, `(?m)^\s*(if|for|while|class) .*:$`, `\bprint\(`)},
+	{"javascript", compilePatterns(`\b(const|let|var) \w+ = `, `=> \{?`, `\bfunction \w*\(`, `\bconsole\.log\(`, `\brequire\(`)},
+	{"typescript", compilePatterns(`\binterface \w+ \{`, `: (string|number|boolean)\b`, `(?m)^export (type|interface) `)},
+	{"rust", compilePatterns(`\bfn \w+\(`, `\blet mut\b`, `\bimpl\b`, `\bprintln!\(`, `(?m)^use \w+::`)},
+	{"c", compilePatterns(`(?m)^#include [<"]`, `\bint main\(`, `\bprintf\(`, `(?m)^#define `)},
+	{"java", compilePatterns(`\bpublic (static )?(class|void)\b`, `\bSystem\.out\.`, `(?m)^import java\.`)},
+	{"shell", compilePatterns(`(?m)^#!/(usr/)?bin/`, `(?m)^\$ `, `\bsudo \w+`, `\| ?(grep|awk|sed|xargs)\b`, `(?m)^(export \w+=|echo |cd |ls |apt |git |docker )`)},
+	{"sql", compilePatterns(`(?i)\bselect\b[\s\S]+\bfrom\b`, `(?i)\bwhere\b`, `(?i)\binsert into\b`, `(?i)\bcreate (table|index)\b`, `(?i)\bupdate \w+ set\b`)},
+	{"html", compilePatterns(`(?i)<(!doctype|html|head|body|div|span|p|a|script|style)\b`, `</\w+>`)},
+	{"css", compilePatterns(`(?m)^[.#]?[\w\-]+( [.#]?[\w\-]+)* \{$`, `(?m)^\s+[\w\-]+: [^;]+;$`)},
+	{"yaml", compilePatterns(`(?m)^[\w\-]+:( .+)?$`, `(?m)^\s+- \w+`)},
+}
+
+func compilePatterns(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+func classifyContent(content string, itemType byte) (string, string) {
+	if itemType == 2 {
+		return kindImage, ""
+	}
+
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return kindText, ""
+	}
+
+	if !strings.ContainsAny(text, "\n\t") {
+		switch {
+		case isURL(text):
+			return kindURL, ""
+		case emailPattern.MatchString(text):
+			return kindEmail, ""
+		case strings.HasPrefix(text, "file://") || (pathPattern.MatchString(text) && !strings.Contains(text, "//")):
+			return kindPath, ""
+		case colorPattern.MatchString(text):
+			return kindColor, ""
+		case numberPattern.MatchString(text):
+			return kindNumber, ""
+		case phonePattern.MatchString(text) && isPhoneNumber(text) && !datePattern.MatchString(text):
+			return kindPhone, ""
+		}
+	}
+
+	if (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) && json.Valid([]byte(text)) {
+		return kindJSON, ""
+	}
+
+	if language := guessLanguage(text); language != "" {
+		return kindCode, language
+	}
+
+	return kindText, ""
+}
+
+func isURL(text string) bool {
+	if strings.ContainsAny(text, " <>\"") {
+		return false
+	}
+	if strings.HasPrefix(text, "www.") && strings.Count(text, ".") >= 2 {
+		return true
+	}
+	parsed, err := url.Parse(text)
+	if err != nil {
+		return false
+	}
+	switch parsed.Scheme {
+	case "http", "https", "ftp", "ftps", "ssh", "git":
+		return parsed.Host != ""
+	}
+	return false
+}
+
+func isPhoneNumber(text string) bool {
+	digits := 0
+	for _, char := range text {
+		if char >= '0' && char <= '9' {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+func guessLanguage(text string) string {
+	bestLanguage := ""
+	bestScore := 0
+	for _, rule := range languageRules {
+		score := 0
+		for _, pattern := range rule.patterns {
+			if pattern.MatchString(text) {
+				score++
+			}
+		}
+		if score > bestScore {
+			bestLanguage = rule.language
+			bestScore = score
+		}
+	}
+
+	lines := strings.Count(text, "\n") + 1
+	if bestScore >= 2 || (bestScore == 1 && lines > 1 && looksLikeCode(text)) {
+		return bestLanguage
+	}
+
+	return ""
+}
+
+func looksLikeCode(text string) bool {
+	codeLines := 0
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, ";") || strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "}") || strings.HasSuffix(trimmed, ":") || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			codeLines++
+		}
+	}
+	return codeLines*2 >= len(lines)
+}
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CLI struct{}
+
+func (cli *CLI) run(args []string) int {
+	if len(args) == 0 {
+		cli.usage()
+		return 2
+	}
+
+	switch args[0] {
+	case "search":
+		return cli.search(args[1:])
+	case "export":
+		return cli.export(args[1:])
+	case "tag":
+		return cli.tag(args[1:])
+	case "import":
+		return cli.importHistory(args[1:])
+	case "copy":
+		return cli.copy(args[1:])
+	case "transforms":
+		return cli.listTransforms()
+	case "snippet":
+		return cli.snippet(args[1:])
+	case "backup":
+		return cli.backup(args[1:])
+	case "restore":
+		return cli.restore(args[1:])
+	case "clear":
+		return cli.clear(args[1:])
+	case "ignore":
+		return cli.ignore(args[1:])
+	case "stats":
+		return cli.stats()
+	case "help", "-h", "--help":
+		cli.usage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "clyp: unknown command %q\n\n", args[0])
+		cli.usage()
+		return 2
+	}
+}
+
+func (cli *CLI) usage() {
+	fmt.Fprint(os.Stderr, `Usage: clyp [command] [options]
+
+Without a command, the main window is opened.
+
+Commands:
+  watch                          Run the clipboard watcher
+  popup                          Open the quick-paste popup
+  search [--regex] <query>       Search clipboard history
+  export [options]               Export clipboard history
+  tag add|remove <id> <tag>...   Add or remove item tags
+  tag list [id]                  List tags
+  import <source> [path]         Import history from another clipboard manager
+                                 (clyp, copyq, gpaste, cliphist, clipman, klipper)
+  copy [--transform t1,t2] <id>  Copy an item, optionally transformed
+  transforms                     List available transforms
+  snippet list                   List snippets
+  snippet add <name> [content]   Add a snippet, content is read from stdin if omitted
+  snippet remove <name>          Remove a snippet
+  snippet show|copy <name>       Print or copy a snippet with placeholders expanded
+  backup [--list]                Back up the database or list backups
+  restore <file>                 Restore the database from a backup
+  clear [options]                Permanently delete history, all of it without options
+  ignore add|remove <app>        Stop or resume recording copies from an application
+  ignore list                    List ignored applications
+  stats                          Show usage statistics
+  help                           Show this help
+`)
+}
+
+func (cli *CLI) flagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("clyp "+name, flag.ContinueOnError)
+	flagSet.SetOutput(os.Stderr)
+	return flagSet
+}
+
+func (cli *CLI) fail(err error) int {
+	fmt.Fprintf(os.Stderr, "clyp: %v\n", err)
+	return 1
+}
+
+func (cli *CLI) search(args []string) int {
+	flagSet := cli.flagSet("search")
+	isRegex := flagSet.Bool("regex", false, "treat the query as a regular expression")
+	limit := flagSet.Int("limit", 30, "maximum number of items to show")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := strings.Join(flagSet.Args(), " ")
+	if *isRegex && filter == "" {
+		return cli.fail(fmt.Errorf("missing pattern"))
+	}
+	if *isRegex {
+		filter = "/" + filter + "/"
+	}
+
+	items, err := clipboard.search(filter, *limit)
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	cli.printItems(items)
+
+	return 0
+}
+
+func (cli *CLI) itemFilterFlags(flagSet *flag.FlagSet) func() (ItemFilter, error) {
+	since := flagSet.String("since", "", "only items newer than a duration (e.g. 7d, 12h) or date (YYYY-MM-DD)")
+	itemType := flagSet.String("type", "", "only items of a type (text, image)")
+	tag := flagSet.String("tag", "", "only items with a tag")
+
+	return func() (ItemFilter, error) {
+		filter := ItemFilter{tag: *tag}
+		if *since != "" {
+			sinceTime, err := parseTimeValue(*since)
+			if err != nil {
+				return filter, err
+			}
+			filter.since = sinceTime
+		}
+		if *itemType != "" {
+			parsedType, err := parseItemType(*itemType)
+			if err != nil {
+				return filter, err
+			}
+			filter.itemType = parsedType
+		}
+		return filter, nil
+	}
+}
+
+func parseTimeValue(value string) (time.Time, error) {
+	if date, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return date, nil
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+
+	units := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if len(value) > 1 {
+		if unit, ok := units[value[len(value)-1]]; ok {
+			if amount, err := strconv.Atoi(value[:len(value)-1]); err == nil && amount >= 0 {
+				return time.Now().Add(-time.Duration(amount) * unit), nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a duration like 7d or a date like 2006-01-02", value)
+}
+
+func (cli *CLI) export(args []string) int {
+	flagSet := cli.flagSet("export")
+	format := flagSet.String("format", "json", "output format (json, ndjson, csv, md)")
+	outputDir := flagSet.String("output-dir", "", "write the export and images as sidecar PNG files into a directory")
+	itemFilter := cli.itemFilterFlags(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+
+	extension, ok := exportFormats[*format]
+	if !ok {
+		return cli.fail(fmt.Errorf("unknown export format %q", *format))
+	}
+
+	filter, err := itemFilter()
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	items, err := clipboard.filteredItems(filter)
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	export := Export{format: *format}
+	if *outputDir == "" {
+		if err := export.write(os.Stdout, items); err != nil {
+			return cli.fail(err)
+		}
+		return 0
+	}
+
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return cli.fail(err)
+	}
+	export.imageDir = filepath.Join(*outputDir, "images")
+	outputPath := filepath.Join(*outputDir, "clyp-export"+extension)
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return cli.fail(err)
+	}
+	defer file.Close()
+
+	if err := export.write(file, items); err != nil {
+		return cli.fail(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d items to %s\n", len(items), outputPath)
+
+	return 0
+}
+
+func (cli *CLI) tag(args []string) int {
+	if len(args) == 0 {
+		cli.usage()
+		return 2
+	}
+
+	if args[0] == "list" {
+		return cli.listTags(args[1:])
+	}
+
+	if len(args) < 3 || (args[0] != "add" && args[0] != "remove") {
+		cli.usage()
+		return 2
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return cli.fail(fmt.Errorf("invalid item id %q", args[1]))
+	}
+
+	if args[0] == "add" {
+		err = clipboard.addTags(id, args[2:])
+	} else {
+		err = clipboard.removeTags(id, args[2:])
+	}
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	return 0
+}
+
+func (cli *CLI) listTags(args []string) int {
+	if len(args) == 0 {
+		tags, err := clipboard.allTags()
+		if err != nil {
+			return cli.fail(err)
+		}
+		names := make([]string, 0, len(tags))
+		for tag := range tags {
+			names = append(names, tag)
+		}
+		slices.Sort(names)
+		for _, tag := range names {
+			fmt.Printf("%s\t%d\n", tag, tags[tag])
+		}
+		return 0
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return cli.fail(fmt.Errorf("invalid item id %q", args[0]))
+	}
+	tags, err := clipboard.tags(id)
+	if err != nil {
+		return cli.fail(err)
+	}
+	for _, tag := range tags {
+		fmt.Println(tag)
+	}
+
+	return 0
+}
+
+func (cli *CLI) importHistory(args []string) int {
+	flagSet := cli.flagSet("import")
+	dryRun := flagSet.Bool("dry-run", false, "read the source without importing")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+	if flagSet.NArg() == 0 || flagSet.NArg() > 2 {
+		cli.usage()
+		return 2
+	}
+
+	sourceName := flagSet.Arg(0)
+	source, ok := importSources[sourceName]
+	if !ok {
+		return cli.fail(fmt.Errorf("unknown import source %q, expected one of: %s", sourceName, strings.Join(importSourceNames(), ", ")))
+	}
+
+	path := flagSet.Arg(1)
+	if path == "" && source.defaultPath != nil {
+		path = source.defaultPath()
+	}
+	if path == "" && sourceName == "clyp" {
+		return cli.fail(fmt.Errorf("missing path to a clyp JSON or NDJSON export"))
+	}
+
+	entries, err := source.read(path)
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Found %d entries\n", len(entries))
+		return 0
+	}
+
+	result, err := clipboard.importEntries(entries)
+	if err != nil {
+		return cli.fail(err)
+	}
+	ipc.notify()
+
+	fmt.Printf("Imported: %d, skipped: %d, failed: %d\n", result.imported, result.skipped, result.failed)
+
+	return 0
+}
+
+func (cli *CLI) copy(args []string) int {
+	flagSet := cli.flagSet("copy")
+	transformNames := flagSet.String("transform", "", "comma separated transforms to apply, see \"clyp transforms\"")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+	if flagSet.NArg() != 1 {
+		cli.usage()
+		return 2
+	}
+
+	request := IPCRequest{Command: "copy", ID: flagSet.Arg(0)}
+	if *transformNames != "" {
+		request.Transforms = strings.Split(*transformNames, ",")
+		for _, name := range request.Transforms {
+			if _, err := findTransform(name); err != nil {
+				return cli.fail(err)
+			}
+		}
+	}
+
+	if err := ipc.request(request); err != nil {
+		return cli.fail(err)
+	}
+
+	return 0
+}
+
+func (cli *CLI) listTransforms() int {
+	for _, transform := range transforms {
+		fmt.Printf("%-22s %s\n", transform.name, transform.label)
+	}
+	return 0
+}
+
+type inputValues map[string]string
+
+func (values inputValues) String() string {
+	return fmt.Sprint(map[string]string(values))
+}
+
+func (values inputValues) Set(value string) error {
+	name, inputValue, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected Name=value")
+	}
+	values[name] = inputValue
+	return nil
+}
+
+func (cli *CLI) snippet(args []string) int {
+	if len(args) == 0 {
+		cli.usage()
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		items, err := snippets.list()
+		if err != nil {
+			return cli.fail(err)
+		}
+		for _, snippet := range items {
+			fmt.Printf("%s\t%s\n", snippet.name, strings.ReplaceAll(snippet.content, "\n", `\n`))
+		}
+		return 0
+	case "add":
+		if len(args) < 2 {
+			cli.usage()
+			return 2
+		}
+		content := strings.Join(args[2:], " ")
+		if len(args) == 2 {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return cli.fail(err)
+			}
+			content = strings.TrimSuffix(string(data), "\n")
+		}
+		if err := snippets.save(0, args[1], content); err != nil {
+			return cli.fail(err)
+		}
+		return 0
+	case "remove":
+		if len(args) != 2 {
+			cli.usage()
+			return 2
+		}
+		snippet, err := snippets.get(args[1])
+		if err != nil {
+			return cli.fail(err)
+		}
+		if err := snippets.remove(snippet.id); err != nil {
+			return cli.fail(err)
+		}
+		return 0
+	case "show", "copy":
+		return cli.expandSnippet(args[0], args[1:])
+	default:
+		cli.usage()
+		return 2
+	}
+}
+
+func (cli *CLI) expandSnippet(command string, args []string) int {
+	flagSet := cli.flagSet("snippet " + command)
+	inputs := inputValues{}
+	flagSet.Var(inputs, "input", "value for an {{input:Name}} placeholder as Name=value, can be repeated")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+	if flagSet.NArg() != 1 {
+		cli.usage()
+		return 2
+	}
+
+	snippet, err := snippets.get(flagSet.Arg(0))
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for _, name := range snippets.inputs(snippet.content) {
+		if _, ok := inputs[name]; ok {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+		value, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return cli.fail(err)
+		}
+		inputs[name] = strings.TrimRight(value, "\r\n")
+	}
+
+	text := snippets.expand(snippet.content, clipboard.latestText(), inputs)
+
+	if command == "show" {
+		fmt.Println(text)
+		return 0
+	}
+
+	if err := ipc.request(IPCRequest{Command: "copy_text", Text: text}); err != nil {
+		return cli.fail(err)
+	}
+
+	return 0
+}
+
+func (cli *CLI) backup(args []string) int {
+	flagSet := cli.flagSet("backup")
+	list := flagSet.Bool("list", false, "list existing backups")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+
+	if *list {
+		backups, err := database.backups()
+		if err != nil {
+			return cli.fail(err)
+		}
+		for _, backupPath := range backups {
+			fmt.Println(backupPath)
+		}
+		return 0
+	}
+
+	backupPath, err := database.createBackup()
+	if err != nil {
+		return cli.fail(err)
+	}
+	fmt.Println(backupPath)
+
+	return 0
+}
+
+func (cli *CLI) restore(args []string) int {
+	if len(args) != 1 {
+		cli.usage()
+		return 2
+	}
+
+	if err := database.restore(args[0]); err != nil {
+		return cli.fail(err)
+	}
+	ipc.notify()
+
+	fmt.Printf("Restored database from %s\n", args[0])
+
+	return 0
+}
+
+func (cli *CLI) clear(args []string) int {
+	flagSet := cli.flagSet("clear")
+	olderThan := flagSet.String("older-than", "", "only items older than a duration (e.g. 7d, 12h) or date (YYYY-MM-DD)")
+	itemType := flagSet.String("type", "", "only items of a type (text, image)")
+	tag := flagSet.String("tag", "", "only items with a tag")
+	keepPinned := flagSet.Bool("keep-pinned", false, "keep pinned items")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := ItemFilter{tag: *tag, anyState: true, unpinned: *keepPinned}
+	if *olderThan != "" {
+		until, err := parseTimeValue(*olderThan)
+		if err != nil {
+			return cli.fail(err)
+		}
+		filter.until = until
+	}
+	if *itemType != "" {
+		parsedType, err := parseItemType(*itemType)
+		if err != nil {
+			return cli.fail(err)
+		}
+		filter.itemType = parsedType
+	}
+
+	count, err := clipboard.clear(filter)
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	fmt.Printf("Deleted %d items\n", count)
+
+	return 0
+}
+
+func (cli *CLI) ignore(args []string) int {
+	if len(args) == 1 && args[0] == "list" {
+		for _, rule := range config.IgnoredApps {
+			fmt.Println(rule)
+		}
+		return 0
+	}
+
+	if len(args) != 2 || (args[0] != "add" && args[0] != "remove") {
+		cli.usage()
+		return 2
+	}
+
+	var err error
+	if args[0] == "add" {
+		err = addIgnoredApp(args[1])
+	} else {
+		err = removeIgnoredApp(args[1])
+	}
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	return 0
+}
+
+func (cli *CLI) stats() int {
+	stats, err := clipboard.stats()
+	if err != nil {
+		return cli.fail(err)
+	}
+
+	fmt.Printf("Items:         %d texts, %d images, %d pinned, %d in the trash\n", stats.texts, stats.images, stats.pinned, stats.trashed)
+	fmt.Printf("Usage:         %d captures, %d copies from history\n", stats.captures, stats.copies)
+	fmt.Printf("Capture rate:  %.1f items per day over the last %d days\n", stats.captureRate, statsRateDays)
+	fmt.Printf("Database size: %.1f MiB\n", float64(stats.databaseSize)/(1<<20))
+
+	fmt.Printf("\nLast %d days:\n", statsDays)
+	for _, day := range stats.days {
+		fmt.Printf("%s\t%d texts\t%d images\n", day.day, day.texts, day.images)
+	}
+
+	fmt.Println("\nMost frequent items:")
+	for _, item := range stats.topItems {
+		content := item.content
+		if item.itemType == 2 {
+			content = "[image]"
+		}
+		preview := []rune(strings.ReplaceAll(content, "\n", `\n`))
+		if len(preview) > 60 {
+			preview = append(preview[:60], '…')
+		}
+		fmt.Printf("%d\t%d captures\t%d copies\t%s\n", item.id, item.useCount, item.copyCount, string(preview))
+	}
+
+	return 0
+}
+
+func (cli *CLI) printItems(items []ClipboardItem) {
+	for _, item := range items {
+		content := item.content
+		if item.itemType == 2 {
+			content = "[image]"
+		}
+		content = strings.ReplaceAll(content, "\n", `\n`)
+		fmt.Printf("%d\t%s\t%s\n", item.id, item.dateTime, content)
+	}
+}
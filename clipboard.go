@@ -2,40 +2,197 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/diamondburned/gotk4/pkg/core/gioutil"
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 )
 
 type Clipboard struct {
-	clipboard     gdk.Clipboard
-	itemCount     int
-	recentContent string
+	clipboard   gdk.Clipboard
+	itemCount   int
+	recentHash  string
+	recentFrame string
+	paused      bool
 }
 
 type ClipboardItem struct {
-	id       int
-	dateTime string
-	content  string
+	id          int
+	dateTime    string
+	content     string
+	itemType    byte
+	kind        string
+	language    string
+	pinned      bool
+	deletedAt   string
+	sourceApp   string
+	sourceTitle string
+	useCount    int
+	copyCount   int
+	lastUsed    string
+}
+
+const itemColumns = "id, type, date_time, content, kind, language, pinned, IFNULL(deleted_at, ''), source_app, source_title, use_count, copy_count, IFNULL(last_used, '')"
+
+const (
+	sortRecent   = "recent"
+	sortFrequent = "frequent"
+)
+
+// frecencyScore ranks items by how often they were captured and copied,
+// weighted by how recently they were last captured again or copied, or by
+// their first capture when they were never reused.
+const frecencyScore = `(use_count + copy_count) * CASE
+	WHEN julianday('now') - julianday(IFNULL(last_used, date_time)) < 4 THEN 100
+	WHEN julianday('now') - julianday(IFNULL(last_used, date_time)) < 14 THEN 70
+	WHEN julianday('now') - julianday(IFNULL(last_used, date_time)) < 31 THEN 50
+	WHEN julianday('now') - julianday(IFNULL(last_used, date_time)) < 90 THEN 30
+	ELSE 10 END`
+
+var sortOrders = map[string]string{
+	sortRecent:   "date_time DESC",
+	sortFrequent: frecencyScore + " DESC, date_time DESC",
+}
+
+const trashTimeFormat = "2006-01-02 15:04:05.000000"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanItem(row rowScanner) (ClipboardItem, error) {
+	var item ClipboardItem
+	err := row.Scan(&item.id, &item.itemType, &item.dateTime, &item.content, &item.kind, &item.language, &item.pinned, &item.deletedAt, &item.sourceApp, &item.sourceTitle, &item.useCount, &item.copyCount, &item.lastUsed)
+	return item, err
+}
+
+type ItemFilter struct {
+	since    time.Time
+	until    time.Time
 	itemType byte
+	tag      string
+	ids      []string
+	deleted  bool
+	anyState bool
+	unpinned bool
+}
+
+var itemTypeNames = map[byte]string{
+	1: "text",
+	2: "image",
+}
+
+func itemTypeName(itemType byte) string {
+	if name, ok := itemTypeNames[itemType]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func parseItemType(name string) (byte, error) {
+	for itemType, typeName := range itemTypeNames {
+		if typeName == name {
+			return itemType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown item type %q", name)
+}
+
+func (filter ItemFilter) where() (string, []any) {
+	var conditions []string
+	var args []any
+
+	switch {
+	case filter.deleted:
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	case !filter.anyState:
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if !filter.since.IsZero() {
+		conditions = append(conditions, "date_time >= ?")
+		args = append(args, filter.since.UTC().Format(time.DateTime))
+	}
+	if !filter.until.IsZero() {
+		conditions = append(conditions, "date_time < ?")
+		args = append(args, filter.until.UTC().Format(time.DateTime))
+	}
+	if filter.itemType != 0 {
+		conditions = append(conditions, "type = ?")
+		args = append(args, filter.itemType)
+	}
+	if filter.tag != "" {
+		conditions = append(conditions, "id IN (SELECT item_id FROM clipboard_tags WHERE tag = ?)")
+		args = append(args, filter.tag)
+	}
+	if len(filter.ids) > 0 {
+		conditions = append(conditions, "id IN (?"+strings.Repeat(", ?", len(filter.ids)-1)+")")
+		for _, id := range filter.ids {
+			args = append(args, id)
+		}
+	}
+	if filter.unpinned {
+		conditions = append(conditions, "pinned = 0")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
 }
 
 func (clipboard *Clipboard) items(updateItemCount bool) ([]ClipboardItem, error) {
+	items, err := clipboard.sortedSearch(database.searchFilter, 30, config.SortMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if updateItemCount {
+		clipboard.count()
+	}
+
+	return items, nil
+}
+
+func (clipboard *Clipboard) search(filter string, limit int) ([]ClipboardItem, error) {
+	return clipboard.sortedSearch(filter, limit, sortRecent)
+}
+
+func (clipboard *Clipboard) sortedSearch(filter string, limit int, sortMode string) ([]ClipboardItem, error) {
 	var items []ClipboardItem
 	var rows *sql.Rows
 	var err error
 
-	if database.searchFilter != "" {
-		database.query = `SELECT id, type, date_time, content FROM clipboard WHERE type=1 AND content LIKE ? ORDER BY date_time DESC LIMIT 30`
-		rows, err = database.db.Query(database.query, "%"+database.searchFilter+"%")
+	order, ok := sortOrders[sortMode]
+	if !ok {
+		order = sortOrders[sortRecent]
+	}
+
+	if pattern, isRegex := parseSearchFilter(filter); isRegex {
+		if err := validateSearchFilter(filter); err != nil {
+			return nil, err
+		}
+		database.query = `SELECT ` + itemColumns + ` FROM clipboard WHERE deleted_at IS NULL AND type=1 AND content REGEXP ? ORDER BY ` + order + ` LIMIT ?`
+		rows, err = database.db.Query(database.query, pattern, limit)
+	} else if filter != "" {
+		database.query = `SELECT ` + itemColumns + ` FROM clipboard WHERE deleted_at IS NULL AND type=1 AND content LIKE ? ORDER BY ` + order + ` LIMIT ?`
+		rows, err = database.db.Query(database.query, "%"+filter+"%", limit)
 	} else {
-		database.query = database.queryBase
-		rows, err = database.db.Query(database.query)
+		database.query = database.queryBase + ` ORDER BY ` + order + ` LIMIT ?`
+		rows, err = database.db.Query(database.query, limit)
 	}
 
 	if err != nil {
@@ -44,43 +201,104 @@ func (clipboard *Clipboard) items(updateItemCount bool) ([]ClipboardItem, error)
 	defer rows.Close()
 
 	for rows.Next() {
-		var item ClipboardItem
-		if err := rows.Scan(&item.id, &item.itemType, &item.dateTime, &item.content); err != nil {
+		item, err := scanItem(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, item)
 	}
 
-	if updateItemCount {
-		clipboard.count()
+	return items, rows.Err()
+}
+
+func (clipboard *Clipboard) item(id string) (ClipboardItem, error) {
+	item, err := scanItem(database.db.QueryRow("SELECT "+itemColumns+" FROM clipboard WHERE id=? AND deleted_at IS NULL LIMIT 1", id))
+	if err != nil {
+		return item, fmt.Errorf("item %s not found", id)
 	}
+	return item, nil
+}
 
-	return items, nil
+func (clipboard *Clipboard) filteredItems(filter ItemFilter) ([]ClipboardItem, error) {
+	var items []ClipboardItem
+
+	where, args := filter.where()
+	rows, err := database.db.Query("SELECT "+itemColumns+" FROM clipboard"+where+" ORDER BY date_time ASC, id ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func parseSearchFilter(filter string) (string, bool) {
+	if len(filter) > 2 && strings.HasPrefix(filter, "/") && strings.HasSuffix(filter, "/") {
+		return filter[1 : len(filter)-1], true
+	}
+	return filter, false
+}
+
+func validateSearchFilter(filter string) error {
+	pattern, isRegex := parseSearchFilter(filter)
+	if !isRegex {
+		return nil
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
+	}
+	return nil
 }
 
 func (clipboard *Clipboard) count() {
-	rowTotalItemsCount := database.db.QueryRow("SELECT COUNT(*) as total_items FROM clipboard")
+	rowTotalItemsCount := database.db.QueryRow("SELECT COUNT(*) as total_items FROM clipboard WHERE deleted_at IS NULL")
 	rowTotalItemsCount.Scan(&clipboard.itemCount)
 }
 
 func (clipboard *Clipboard) watch() {
 	clipboard.clipboard = *gdk.DisplayGetDefault().Clipboard()
 	clipboard.clipboard.ConnectChanged(func() {
-		formats := clipboard.clipboard.Formats().String()
-		if formats == "" {
+		if clipboard.clipboard.Formats().String() == "" || clipboard.paused {
 			return
 		}
-		if strings.Contains(formats, "text/") {
-			clipboard.readTextContent()
-		} else if strings.Contains(formats, "image/") {
-			clipboard.readImageContent()
-		} else {
-			log.Printf("Unsupported clipboard format: %s", formats)
-		}
+		// The source is detected with external commands, the clipboard is read
+		// once it is known so that ignored applications are never read.
+		go func() {
+			source := detectSourceApp()
+			glib.IdleAdd(func() {
+				clipboard.read(source)
+			})
+		}()
 	})
 }
 
-func (clipboard *Clipboard) readTextContent() {
+func (clipboard *Clipboard) read(source SourceApp) {
+	if source.ignored() {
+		notifier.filtered("Ignored a copy from " + source.label())
+		return
+	}
+	contentFormats := clipboard.clipboard.Formats()
+	formats := contentFormats.String()
+	if strings.Contains(formats, "text/") {
+		clipboard.readTextContent(source)
+	} else if contentFormats.ContainMIMEType("image/png") || contentFormats.ContainMIMEType("image/jpeg") {
+		clipboard.readImageBytes(source)
+	} else if strings.Contains(formats, "image/") {
+		clipboard.readImageContent(source)
+	} else if formats != "" {
+		log.Printf("Unsupported clipboard format: %s", formats)
+	}
+}
+
+func (clipboard *Clipboard) readTextContent(source SourceApp) {
 	clipboard.clipboard.ReadTextAsync(context.Background(), func(result gio.AsyncResulter) {
 		text, err := clipboard.clipboard.ReadTextFinish(result)
 		if err != nil {
@@ -88,29 +306,77 @@ func (clipboard *Clipboard) readTextContent() {
 		}
 		text = strings.TrimSpace(text)
 		if text != "" {
-			clipboard.saveToDatabase(text, 1)
+			clipboard.saveToDatabase(text, 1, source)
 		}
 	})
 }
 
-func (clipboard *Clipboard) readImageContent() {
+// readImageBytes reads the image as encoded by the source application, the
+// stream is read and the image processed outside the main loop.
+func (clipboard *Clipboard) readImageBytes(source SourceApp) {
+	clipboard.clipboard.ReadAsync(context.Background(), []string{"image/png", "image/jpeg"}, glib.PRIORITY_DEFAULT, func(result gio.AsyncResulter) {
+		_, stream, err := clipboard.clipboard.ReadFinish(result)
+		if err != nil {
+			log.Printf("Failed to read image from clipboard: %v", err)
+			return
+		}
+		recentHash, recentFrame := clipboard.recentHash, clipboard.recentFrame
+		go func() {
+			reader := gioutil.Reader(context.Background(), stream)
+			data, err := io.ReadAll(io.LimitReader(reader, imageMaxReadBytes))
+			reader.Close()
+			if err != nil {
+				glib.IdleAdd(func() {
+					log.Printf("Failed to read image from clipboard: %v", err)
+				})
+				return
+			}
+			clipboard.captureImage(data, recentHash, recentFrame, source)
+		}()
+	})
+}
+
+func (clipboard *Clipboard) readImageContent(source SourceApp) {
 	clipboard.clipboard.ReadTextureAsync(context.Background(), func(result gio.AsyncResulter) {
 		texture, err := clipboard.clipboard.ReadTextureFinish(result)
 		if err != nil || texture == nil {
 			return
 		}
 
-		imageData := clipboard.textureToBase64(texture)
-
-		if imageData == "" {
+		imageData := clipboard.texturePNG(texture)
+		if len(imageData) == 0 {
 			return
 		}
 
-		clipboard.saveToDatabase(imageData, 2)
+		go clipboard.captureImage(imageData, clipboard.recentHash, clipboard.recentFrame, source)
 	})
 }
 
-func (clipboard *Clipboard) textureToBase64(texture gdk.Texturer) string {
+// captureImage runs outside the main loop and saves the image on it, images
+// copied back from the history are recognized by recentHash before decoding.
+func (clipboard *Clipboard) captureImage(data []byte, recentHash, recentFrame string, source SourceApp) {
+	if contentHash(base64.StdEncoding.EncodeToString(data), 2) == recentHash {
+		return
+	}
+	captured, err := processImage(data, recentFrame)
+	glib.IdleAdd(func() {
+		switch {
+		case errors.Is(err, errDuplicateFrame):
+			return
+		case errors.Is(err, errImageTooLarge):
+			log.Printf("Skipped image: %v", err)
+			notifier.filtered("Skipped an image, " + err.Error())
+			return
+		case err != nil:
+			log.Printf("Failed to process image: %v", err)
+			return
+		}
+		clipboard.recentFrame = captured.frame
+		clipboard.saveToDatabase(base64.StdEncoding.EncodeToString(captured.data), 2, source)
+	})
+}
+
+func (clipboard *Clipboard) texturePNG(texture gdk.Texturer) []byte {
 	var pngBytes *glib.Bytes
 
 	if memTexture, ok := texture.(*gdk.MemoryTexture); ok {
@@ -121,87 +387,400 @@ func (clipboard *Clipboard) textureToBase64(texture gdk.Texturer) string {
 		if textureSaver, ok := texture.(interface{ SaveToPNGBytes() *glib.Bytes }); ok {
 			pngBytes = textureSaver.SaveToPNGBytes()
 		} else {
-			return ""
+			return nil
 		}
 	}
 
 	if pngBytes == nil {
-		return ""
+		return nil
+	}
+
+	return pngBytes.Data()
+}
+
+// contentHash identifies content regardless of surrounding whitespace and line
+// endings, images are hashed as encoded.
+func contentHash(content string, itemType byte) string {
+	if itemType == 1 {
+		content = strings.ReplaceAll(strings.TrimSpace(content), "\r\n", "\n")
+	}
+	sum := sha256.Sum256(append([]byte{itemType}, content...))
+	return hex.EncodeToString(sum[:])
+}
+
+func (clipboard *Clipboard) updateRecentHashFromDatabase() {
+	hashRow := database.db.QueryRow("SELECT hash FROM clipboard WHERE deleted_at IS NULL ORDER BY date_time DESC, id DESC LIMIT 1")
+	hashRow.Scan(&clipboard.recentHash)
+}
+
+func (clipboard *Clipboard) saveToDatabase(content string, itemType byte, source SourceApp) {
+	if len(content) == 0 {
+		return
 	}
+	hash := contentHash(content, itemType)
+	if hash == clipboard.recentHash {
+		return
+	}
+
+	clipboard.recentHash = hash
 
-	pngData := pngBytes.Data()
-	if len(pngData) == 0 {
-		return ""
+	if itemType == 1 && config.CleanURLs {
+		if cleaned := cleanURL(content); cleaned != content {
+			content = cleaned
+			if config.CleanURLsSetClipboard {
+				clipboard.recentHash = contentHash(cleaned, itemType)
+				clipboard.setText(cleaned)
+			}
+		}
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(pngData)
+	kind, language := classifyContent(content, itemType)
+	hooks.process(HookEntry{content: content, itemType: itemType, kind: kind, language: language, source: source}, clipboard.insertEntry)
+}
 
-	return encoded
+func (clipboard *Clipboard) insertEntry(entry HookEntry) {
+	if _, err := clipboard.insertItem(entry); err != nil {
+		log.Printf("Failed to save clipboard item: %v", err)
+		notifier.error("Failed to save clipboard item: %v", err)
+	}
 }
 
-func (clipboard *Clipboard) updateRecentContentFromDatabase() {
-	contentRow := database.db.QueryRow("SELECT content FROM clipboard ORDER BY id DESC LIMIT 1")
-	contentRow.Scan(&clipboard.recentContent)
+// insertItem adds an entry to the history, an entry that is already in the
+// history or the trash is moved to the top and its usage counter is bumped.
+func (clipboard *Clipboard) insertItem(entry HookEntry) (ClipboardItem, error) {
+	hash := contentHash(entry.content, entry.itemType)
+
+	var id int64
+	err := database.db.QueryRow("SELECT id FROM clipboard WHERE hash=?", hash).Scan(&id)
+	isNew := err == sql.ErrNoRows
+	switch {
+	case isNew:
+		if entry.itemType == 2 && config.MaxImages > 0 {
+			clipboard.pruneImages(config.MaxImages - 1)
+		}
+		result, err := database.db.Exec("INSERT INTO clipboard (content, type, kind, language, source_app, source_title, hash) VALUES (?, ?, ?, ?, ?, ?, ?)", entry.content, entry.itemType, entry.kind, entry.language, entry.source.app, entry.source.title, hash)
+		if err != nil {
+			return ClipboardItem{}, err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return ClipboardItem{}, err
+		}
+	case err != nil:
+		return ClipboardItem{}, err
+	default:
+		_, err := database.db.Exec("UPDATE clipboard SET date_time=CURRENT_TIMESTAMP, last_used=CURRENT_TIMESTAMP, use_count=use_count+1, deleted_at=NULL, imported=0, source_app=COALESCE(NULLIF(?, ''), source_app), source_title=COALESCE(NULLIF(?, ''), source_title) WHERE id=?", entry.source.app, entry.source.title, id)
+		if err != nil {
+			return ClipboardItem{}, err
+		}
+	}
+	ipc.notify()
+
+	item, err := clipboard.item(strconv.FormatInt(id, 10))
+	if err != nil {
+		return ClipboardItem{}, err
+	}
+	if isNew {
+		dbus.itemAdded(item)
+		api.publish("item_added", item)
+	} else {
+		dbus.countChanged()
+		api.publish("item_updated", item)
+	}
+	tray.update()
+	notifier.captured(item)
+
+	return item, nil
 }
 
-func (clipboard *Clipboard) saveToDatabase(content string, itemType byte) {
-	if len(content) == 0 || content == clipboard.recentContent {
+func (clipboard *Clipboard) setPaused(paused bool) {
+	if clipboard.paused == paused {
 		return
 	}
+	clipboard.paused = paused
+	dbus.pausedChanged()
+	tray.update()
+}
+
+// pruneImages keeps the newest captured images, pinned and imported images
+// are not counted and never deleted.
+func (clipboard *Clipboard) pruneImages(keep int) {
+	database.db.Exec("DELETE FROM clipboard WHERE TYPE = 2 AND pinned = 0 AND imported = 0 AND deleted_at IS NULL AND id NOT IN (SELECT id FROM clipboard WHERE TYPE = 2 AND pinned = 0 AND imported = 0 AND deleted_at IS NULL ORDER BY date_time DESC LIMIT ?)", keep)
+}
+
+// clearHistory moves all unpinned items to the trash, moveToTrash notifies
+// the GUI, D-Bus and the tray. The recent hashes are kept so the content that
+// is still on the clipboard is not captured again.
+func (clipboard *Clipboard) clearHistory() (string, int64, error) {
+	return clipboard.moveToTrash(" WHERE deleted_at IS NULL AND pinned = 0", nil)
+}
+
+func (clipboard *Clipboard) countItems(filter ItemFilter) (texts, images int, err error) {
+	where, args := filter.where()
+	err = database.db.QueryRow("SELECT COUNT(*) FILTER (WHERE type = 1), COUNT(*) FILTER (WHERE type = 2) FROM clipboard"+where, args...).Scan(&texts, &images)
+	return texts, images, err
+}
 
-	if itemType == 2 {
-		database.db.Exec("DELETE FROM clipboard WHERE TYPE = 2 AND id NOT IN (SELECT id FROM clipboard WHERE TYPE = 2 ORDER BY date_time DESC LIMIT 2)")
+func (clipboard *Clipboard) clear(filter ItemFilter) (int64, error) {
+	where, args := filter.where()
+
+	tx, err := database.db.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	_, err := database.db.Exec("INSERT INTO clipboard (content, type) VALUES (?, ?)", content, itemType)
-	if err == nil {
-		clipboard.recentContent = content
-		ipc.notify()
+	result, err := tx.Exec("DELETE FROM clipboard"+where, args...)
+	if err != nil {
+		return 0, err
 	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	database.vacuum()
+	clipboard.updateRecentHashFromDatabase()
+	ipc.notify()
+	dbus.countChanged()
+	tray.update()
+
+	return count, nil
+}
+
+func (clipboard *Clipboard) setPinned(id string, pinned bool) error {
+	result, err := database.db.Exec("UPDATE clipboard SET pinned=? WHERE id=?", pinned, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("item %s not found", id)
+	}
+	return nil
 }
 
-func (clipboard *Clipboard) copy(id string) {
+func (clipboard *Clipboard) setPinnedItems(ids []string, pinned bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	where, args := ItemFilter{ids: ids}.where()
+	_, err := database.db.Exec("UPDATE clipboard SET pinned=?"+where, append([]any{pinned}, args...)...)
+	return err
+}
+
+func (clipboard *Clipboard) removeItems(ids []string) (string, int64, error) {
+	if len(ids) == 0 {
+		return "", 0, nil
+	}
+	where, args := ItemFilter{ids: ids}.where()
+	return clipboard.moveToTrash(where, args)
+}
+
+func (clipboard *Clipboard) moveToTrash(where string, args []any) (string, int64, error) {
+	batch := time.Now().UTC().Format(trashTimeFormat)
+	result, err := database.db.Exec("UPDATE clipboard SET deleted_at=?"+where, append([]any{batch}, args...)...)
+	if err != nil {
+		return "", 0, err
+	}
+	clipboard.trashChanged()
+	count, err := result.RowsAffected()
+	return batch, count, err
+}
+
+func (clipboard *Clipboard) restore(batch string) (int64, error) {
+	result, err := database.db.Exec("UPDATE clipboard SET deleted_at=NULL WHERE deleted_at=?", batch)
+	if err != nil {
+		return 0, err
+	}
+	clipboard.trashChanged()
+	return result.RowsAffected()
+}
+
+func (clipboard *Clipboard) restoreItems(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	where, args := ItemFilter{ids: ids, deleted: true}.where()
+	if _, err := database.db.Exec("UPDATE clipboard SET deleted_at=NULL"+where, args...); err != nil {
+		return err
+	}
+	clipboard.trashChanged()
+	return nil
+}
+
+func (clipboard *Clipboard) trashedItems() ([]ClipboardItem, error) {
+	var items []ClipboardItem
+
+	rows, err := database.db.Query("SELECT " + itemColumns + " FROM clipboard WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (clipboard *Clipboard) purgeItems(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	where, args := ItemFilter{ids: ids, deleted: true}.where()
+	result, err := database.db.Exec("DELETE FROM clipboard"+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (clipboard *Clipboard) emptyTrash() (int64, error) {
+	result, err := database.db.Exec("DELETE FROM clipboard WHERE deleted_at IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (clipboard *Clipboard) purgeTrash(days int) (int64, error) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -days).Format(trashTimeFormat)
+	result, err := database.db.Exec("DELETE FROM clipboard WHERE deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (clipboard *Clipboard) trashChanged() {
+	ipc.notify()
+	dbus.countChanged()
+	tray.update()
+}
+
+func (clipboard *Clipboard) mergeItems(ids []string, separator string) (ClipboardItem, error) {
+	items, err := clipboard.filteredItems(ItemFilter{itemType: 1, ids: ids})
+	if err != nil {
+		return ClipboardItem{}, err
+	}
+	if len(items) < 2 {
+		return ClipboardItem{}, fmt.Errorf("select at least two text items to merge")
+	}
+
+	contents := make([]string, 0, len(items))
+	mergedIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		contents = append(contents, item.content)
+		mergedIDs = append(mergedIDs, strconv.Itoa(item.id))
+	}
+	content := strings.Join(contents, separator)
+	kind, language := classifyContent(content, 1)
+
+	merged, err := clipboard.insertItem(HookEntry{content: content, itemType: 1, kind: kind, language: language})
+	if err != nil {
+		return ClipboardItem{}, err
+	}
+	if _, _, err := clipboard.removeItems(mergedIDs); err != nil {
+		return merged, err
+	}
+	return merged, nil
+}
+
+func (clipboard *Clipboard) copy(id string, transformNames ...string) error {
 	if id == "" {
-		return
+		return nil
 	}
 
 	var content string
 	var itemType byte
-	row := database.db.QueryRow("SELECT content, type FROM clipboard WHERE id=? LIMIT 1", id)
-	row.Scan(&content, &itemType)
+	row := database.db.QueryRow("SELECT content, type FROM clipboard WHERE id=? AND deleted_at IS NULL LIMIT 1", id)
+	if err := row.Scan(&content, &itemType); err != nil {
+		return fmt.Errorf("item %s not found", id)
+	}
+
+	if len(transformNames) > 0 {
+		if itemType != 1 {
+			return fmt.Errorf("transforms can only be applied to text items")
+		}
+		// The transformed text is copied in place of the item and counted as
+		// its copy.
+		transformed, err := applyTransforms(content, transformNames)
+		if err != nil {
+			return err
+		}
+		content = transformed
+	}
 
+	clipboard.skipCapture(contentHash(content, itemType))
 	clipboardInstance := gdk.DisplayGetDefault().Clipboard()
 
 	switch itemType {
 	case 1:
 		clipboardInstance.SetText(content)
-		clipboard.updateItemDateTime(id)
+		clipboard.recordCopy(id)
 	case 2:
 		decoded, err := base64.StdEncoding.DecodeString(content)
 		if err != nil {
 			log.Printf("Failed to decode base64 image data: %v", err)
-			return
+			return err
 		}
-		texture, err := gdk.NewTextureFromBytes(glib.NewBytesWithGo(decoded))
+		imageBytes := glib.NewBytesWithGo(decoded)
+		texture, err := gdk.NewTextureFromBytes(imageBytes)
 		if err != nil {
 			log.Printf("Failed to create texture from bytes: %v", err)
-			return
+			return err
 		}
-		clipboardInstance.SetTexture(texture)
-		clipboard.updateItemDateTime(id)
+		// The stored bytes are offered as they are, the texture converts them
+		// for applications asking for other formats.
+		providers := []*gdk.ContentProvider{gdk.NewContentProviderForValue(glib.NewValue(texture))}
+		if mimeType := imageMIMEType(decoded); mimeType != "" {
+			providers = append([]*gdk.ContentProvider{gdk.NewContentProviderForBytes(mimeType, imageBytes)}, providers...)
+		}
+		clipboardInstance.SetContent(gdk.NewContentProviderUnion(providers))
+		clipboard.recordCopy(id)
 	}
 
 	clipboardInstance = nil
+	notifier.copied(content, itemType)
+
+	return nil
+}
+
+// skipCapture keeps the watcher from capturing content copied from the
+// history, the copy is counted by recordCopy instead. Copies made in the main
+// window tell the watcher before the clipboard is set.
+func (clipboard *Clipboard) skipCapture(hash string) {
+	clipboard.recentHash = hash
+	if gui.window != nil {
+		ipc.request(IPCRequest{Command: "skip_capture", Hash: hash})
+	}
 }
 
-func (clipboard *Clipboard) updateItemDateTime(id string) {
+func (clipboard *Clipboard) setText(text string) {
+	gdk.DisplayGetDefault().Clipboard().SetText(text)
+}
+
+func (clipboard *Clipboard) latestText() string {
+	var content string
+	database.db.QueryRow("SELECT content FROM clipboard WHERE deleted_at IS NULL AND type=1 ORDER BY date_time DESC LIMIT 1").Scan(&content)
+	return content
+}
+
+func (clipboard *Clipboard) recordCopy(id string) {
 	if id == "" {
 		return
 	}
 
-	_, err := database.db.Exec("UPDATE clipboard SET date_time=CURRENT_TIMESTAMP WHERE id=?", id)
+	_, err := database.db.Exec("UPDATE clipboard SET date_time=CURRENT_TIMESTAMP, last_used=CURRENT_TIMESTAMP, copy_count=copy_count+1 WHERE id=?", id)
 	if err != nil {
-		log.Printf("Failed to update item date time: %v", err)
+		log.Printf("Failed to record item copy: %v", err)
 		return
 	}
 }
@@ -210,5 +789,15 @@ func (clipboard *Clipboard) removeFromDatabase(id string) {
 	if id == "" {
 		return
 	}
-	database.db.Exec("DELETE FROM clipboard WHERE id=?", id)
+	database.db.Exec("UPDATE clipboard SET deleted_at=? WHERE id=? AND deleted_at IS NULL", time.Now().UTC().Format(trashTimeFormat), id)
+}
+
+// deleteItem moves a single item to the trash for the API and D-Bus, and
+// notifies the windows and subscribers.
+func (clipboard *Clipboard) deleteItem(item ClipboardItem) {
+	clipboard.removeFromDatabase(strconv.Itoa(item.id))
+	ipc.notify()
+	dbus.countChanged()
+	tray.update()
+	api.publish("item_deleted", item)
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+type Config struct {
+	BackupCount           int               `json:"backup_count"`
+	BackupIntervalMinutes int               `json:"backup_interval_minutes"`
+	HookTimeoutSeconds    int               `json:"hook_timeout_seconds"`
+	CleanURLs             bool              `json:"clean_urls"`
+	CleanURLsSetClipboard bool              `json:"clean_urls_set_clipboard"`
+	URLRules              []URLRule         `json:"url_rules,omitempty"`
+	APIEnabled            bool              `json:"api_enabled"`
+	APIAddress            string            `json:"api_address,omitempty"`
+	APIToken              string            `json:"api_token,omitempty"`
+	AutoPaste             bool              `json:"auto_paste"`
+	PasteBackend          string            `json:"paste_backend"`
+	PasteDelayMS          int               `json:"paste_delay_ms"`
+	GlobalShortcuts       bool              `json:"global_shortcuts"`
+	ShortcutTriggers      map[string]string `json:"shortcut_triggers,omitempty"`
+	TrayIcon              bool              `json:"tray_icon"`
+	NotifyCaptured        bool              `json:"notify_captured"`
+	NotifyCopied          bool              `json:"notify_copied"`
+	NotifyFiltered        bool              `json:"notify_filtered"`
+	NotifyErrors          bool              `json:"notify_errors"`
+	NotifyIntervalSeconds int               `json:"notify_interval_seconds"`
+	MergeSeparator        string            `json:"merge_separator"`
+	TrashDays             int               `json:"trash_days"`
+	SortMode              string            `json:"sort_mode"`
+	MaxImages             int               `json:"max_images"`
+	IgnoredApps           []string          `json:"ignored_apps,omitempty"`
+	ImageMaxDimension     int               `json:"image_max_dimension"`
+	ImageMaxSizeKB        int               `json:"image_max_size_kb"`
+	ImageOversize         string            `json:"image_oversize"`
+	ImageFormat           string            `json:"image_format"`
+	ImageQuality          int               `json:"image_quality"`
+	Actions               []CustomAction    `json:"actions,omitempty"`
+}
+
+func (config *Config) dir() string {
+	return glib.GetUserConfigDir() + "/clyp"
+}
+
+func (config *Config) path() string {
+	return config.dir() + "/config.json"
+}
+
+func (config *Config) setDefaults() {
+	config.BackupCount = 5
+	config.BackupIntervalMinutes = 60
+	config.HookTimeoutSeconds = 5
+	config.APIEnabled = true
+	config.PasteBackend = "auto"
+	config.PasteDelayMS = 200
+	config.GlobalShortcuts = true
+	config.TrayIcon = true
+	config.NotifyFiltered = true
+	config.NotifyErrors = true
+	config.NotifyIntervalSeconds = 5
+	config.MergeSeparator = "\n"
+	config.TrashDays = 7
+	config.SortMode = sortRecent
+	config.MaxImages = 3
+	config.ImageMaxDimension = 2560
+	config.ImageMaxSizeKB = 4096
+	config.ImageOversize = imageOversizeDownscale
+	config.ImageFormat = imageFormatOriginal
+	config.ImageQuality = 85
+}
+
+func (config *Config) load() {
+	config.setDefaults()
+
+	data, err := os.ReadFile(config.path())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read config file: %v", err)
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		log.Printf("Failed to parse config file: %v", err)
+	}
+}
+
+func (config *Config) save() error {
+	if err := os.MkdirAll(config.dir(), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(config.path(), append(data, '\n'), 0644)
+}
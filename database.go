@@ -2,8 +2,12 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 type Database struct {
@@ -13,9 +17,107 @@ type Database struct {
 	searchFilter string
 }
 
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS clipboard_tags (
+	item_id INTEGER NOT NULL REFERENCES clipboard(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (item_id, tag)
+);
+CREATE INDEX IF NOT EXISTS clipboard_tags_tag_IDX ON clipboard_tags (tag);`,
+	`CREATE TABLE IF NOT EXISTS snippets (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	content TEXT NOT NULL,
+	date_time TEXT DEFAULT (CURRENT_TIMESTAMP) NOT NULL
+);`,
+	`ALTER TABLE clipboard ADD COLUMN kind TEXT DEFAULT ('text') NOT NULL;
+ALTER TABLE clipboard ADD COLUMN language TEXT DEFAULT ('') NOT NULL;
+UPDATE clipboard SET kind = clyp_kind(content, type), language = clyp_language(content, type);
+CREATE INDEX IF NOT EXISTS clipboard_kind_IDX ON clipboard (kind);`,
+	`ALTER TABLE clipboard ADD COLUMN pinned INTEGER DEFAULT (0) NOT NULL;`,
+	`ALTER TABLE clipboard ADD COLUMN deleted_at TEXT;
+CREATE INDEX IF NOT EXISTS clipboard_deleted_at_IDX ON clipboard (deleted_at);`,
+	`ALTER TABLE clipboard ADD COLUMN source_app TEXT DEFAULT ('') NOT NULL;
+ALTER TABLE clipboard ADD COLUMN source_title TEXT DEFAULT ('') NOT NULL;`,
+	`ALTER TABLE clipboard ADD COLUMN hash TEXT;
+ALTER TABLE clipboard ADD COLUMN use_count INTEGER DEFAULT (1) NOT NULL;
+UPDATE clipboard SET hash = clyp_hash(content, type);
+UPDATE clipboard SET
+	use_count = (SELECT COUNT(*) FROM clipboard AS duplicate WHERE duplicate.hash = clipboard.hash),
+	pinned = (SELECT MAX(pinned) FROM clipboard AS duplicate WHERE duplicate.hash = clipboard.hash),
+	deleted_at = CASE WHEN EXISTS (SELECT 1 FROM clipboard AS duplicate WHERE duplicate.hash = clipboard.hash AND duplicate.deleted_at IS NULL) THEN NULL ELSE deleted_at END;
+UPDATE OR IGNORE clipboard_tags SET item_id = (SELECT newest.id FROM clipboard AS newest WHERE newest.hash = (SELECT hash FROM clipboard WHERE id = clipboard_tags.item_id) ORDER BY newest.date_time DESC, newest.id DESC LIMIT 1);
+DELETE FROM clipboard WHERE EXISTS (SELECT 1 FROM clipboard AS newer WHERE newer.hash = clipboard.hash AND (newer.date_time > clipboard.date_time OR (newer.date_time = clipboard.date_time AND newer.id > clipboard.id)));
+DROP INDEX IF EXISTS clipboard_content_IDX;
+CREATE UNIQUE INDEX IF NOT EXISTS clipboard_hash_IDX ON clipboard (hash);`,
+	`ALTER TABLE clipboard ADD COLUMN copy_count INTEGER DEFAULT (0) NOT NULL;
+ALTER TABLE clipboard ADD COLUMN last_used TEXT;`,
+	`ALTER TABLE clipboard ADD COLUMN imported INTEGER DEFAULT (0) NOT NULL;`,
+}
+
+// regexpCacheSize bounds the compiled patterns kept for the regexp function,
+// the cache is emptied when it is full.
+const regexpCacheSize = 32
+
+var (
+	regexpCache      = map[string]*regexp.Regexp{}
+	regexpCacheMutex sync.Mutex
+)
+
+func init() {
+	sql.Register("sqlite3_clyp", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("regexp", regexpMatch, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("clyp_kind", classifyKind, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("clyp_hash", hashContent, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("clyp_language", classifyLanguage, true)
+		},
+	})
+}
+
+func regexpMatch(pattern, content string) (bool, error) {
+	regexpCacheMutex.Lock()
+	re, ok := regexpCache[pattern]
+	if !ok {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			regexpCacheMutex.Unlock()
+			return false, err
+		}
+		if len(regexpCache) >= regexpCacheSize {
+			clear(regexpCache)
+		}
+		regexpCache[pattern] = re
+	}
+	regexpCacheMutex.Unlock()
+
+	return re.MatchString(content), nil
+}
+
+func classifyKind(content string, itemType int) string {
+	kind, _ := classifyContent(content, byte(itemType))
+	return kind
+}
+
+func hashContent(content string, itemType int) string {
+	return contentHash(content, byte(itemType))
+}
+
+func classifyLanguage(content string, itemType int) string {
+	_, language := classifyContent(content, byte(itemType))
+	return language
+}
+
 func (database *Database) init() error {
 	database.searchFilter = ""
-	database.queryBase = "SELECT id, type, date_time, content FROM clipboard ORDER BY date_time DESC LIMIT 30"
+	database.queryBase = "SELECT " + itemColumns + " FROM clipboard WHERE deleted_at IS NULL"
 	if err := database.connect(); err != nil {
 		return err
 	}
@@ -24,23 +126,43 @@ func (database *Database) init() error {
 }
 
 func (database *Database) connect() error {
-	dbPath := app.dataDir + "/clyp.db"
-
-	var err error
-	database.db, err = sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return err
-	}
-
-	if err := database.db.Ping(); err != nil {
+	if err := database.open(); err != nil {
 		return err
 	}
 
 	database.create()
+	database.migrate()
 
 	return nil
 }
 
+// checkIntegrity replaces a corrupt database with the newest good backup. It
+// scans the whole database and is only run when the watcher starts, other
+// processes must not move the file while the watcher has it open.
+func (database *Database) checkIntegrity() error {
+	err := integrityCheck(database.db)
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("Database %v", err)
+	database.db.Close()
+	if err := database.recoverFromBackup(); err != nil {
+		log.Printf("Failed to restore database from backup: %v", err)
+	}
+	return database.connect()
+}
+
+func (database *Database) open() error {
+	var err error
+	database.db, err = sql.Open("sqlite3_clyp", database.path()+"?_foreign_keys=on")
+	if err != nil {
+		return err
+	}
+
+	return database.db.Ping()
+}
+
 func (database *Database) create() {
 	database.db.Exec(`
 CREATE TABLE clipboard (
@@ -54,6 +176,35 @@ CREATE UNIQUE INDEX clipboard_content_IDX ON clipboard (content,date_time);
 `)
 }
 
+func (database *Database) migrate() {
+	var version int
+	database.db.QueryRow("PRAGMA user_version").Scan(&version)
+
+	for ; version < len(migrations); version++ {
+		if err := database.applyMigration(version); err != nil {
+			log.Printf("Failed to migrate database to version %d: %v", version+1, err)
+			return
+		}
+	}
+}
+
+func (database *Database) applyMigration(version int) error {
+	tx, err := database.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrations[version]); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (database *Database) vacuum() {
 	database.db.Exec(`VACUUM;`)
 }
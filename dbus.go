@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	coreglib "github.com/diamondburned/gotk4/pkg/core/glib"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const dbusInterface = "bio.murat.clyp.History"
+
+const dbusIntrospection = `<node>
+  <interface name="bio.murat.clyp.History">
+    <method name="List">
+      <arg name="limit" type="u" direction="in"/>
+      <arg name="items" type="a(xsssss)" direction="out"/>
+    </method>
+    <method name="Get">
+      <arg name="id" type="x" direction="in"/>
+      <arg name="item" type="(xsssss)" direction="out"/>
+    </method>
+    <method name="Search">
+      <arg name="query" type="s" direction="in"/>
+      <arg name="limit" type="u" direction="in"/>
+      <arg name="items" type="a(xsssss)" direction="out"/>
+    </method>
+    <method name="Copy">
+      <arg name="id" type="x" direction="in"/>
+    </method>
+    <method name="Delete">
+      <arg name="id" type="x" direction="in"/>
+    </method>
+    <property name="Paused" type="b" access="readwrite"/>
+    <property name="Count" type="u" access="read"/>
+    <signal name="ItemAdded">
+      <arg name="item" type="(xsssss)"/>
+    </signal>
+  </interface>
+</node>`
+
+const dbusDefaultLimit = 30
+
+type DBus struct {
+	connection *gio.DBusConnection
+	objectPath string
+}
+
+func (dbus *DBus) register(connection *gio.DBusConnection, objectPath string) error {
+	nodeInfo, err := gio.NewDBusNodeInfoForXML(dbusIntrospection)
+	if err != nil {
+		return err
+	}
+
+	_, err = connection.RegisterObject(objectPath, nodeInfo.LookupInterface(dbusInterface), dbus.handleMethodCall, dbus.getProperty, dbus.setProperty)
+	if err != nil {
+		return err
+	}
+
+	dbus.connection = connection
+	dbus.objectPath = objectPath
+
+	return nil
+}
+
+func (dbus *DBus) handleMethodCall(connection *gio.DBusConnection, sender, objectPath, interfaceName, methodName string, parameters *glib.Variant, invocation *gio.DBusMethodInvocation) {
+	result, err := dbus.call(methodName, parameters)
+	if err != nil {
+		invocation.ReturnDBusError(dbusInterface+".Error", err.Error())
+		return
+	}
+	invocation.ReturnValue(result)
+}
+
+func (dbus *DBus) call(methodName string, parameters *glib.Variant) (*glib.Variant, error) {
+	switch methodName {
+	case "List":
+		items, err := clipboard.search("", dbusLimit(parameters.ChildValue(0).Uint32()))
+		if err != nil {
+			return nil, err
+		}
+		return glib.NewVariantTuple([]*glib.Variant{dbusItems(items, false)}), nil
+	case "Get":
+		item, err := clipboard.item(strconv.FormatInt(parameters.ChildValue(0).Int64(), 10))
+		if err != nil {
+			return nil, err
+		}
+		return glib.NewVariantTuple([]*glib.Variant{dbusItem(item, true)}), nil
+	case "Search":
+		items, err := clipboard.search(parameters.ChildValue(0).String(), dbusLimit(parameters.ChildValue(1).Uint32()))
+		if err != nil {
+			return nil, err
+		}
+		return glib.NewVariantTuple([]*glib.Variant{dbusItems(items, false)}), nil
+	case "Copy":
+		if err := clipboard.copy(strconv.FormatInt(parameters.ChildValue(0).Int64(), 10)); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	case "Delete":
+		item, err := clipboard.item(strconv.FormatInt(parameters.ChildValue(0).Int64(), 10))
+		if err != nil {
+			return nil, err
+		}
+		clipboard.deleteItem(item)
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown method %q", methodName)
+	}
+}
+
+// getProperty wraps the variant in a GValue, closures can not return variants.
+func (dbus *DBus) getProperty(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string) *coreglib.Value {
+	return glib.NewVariantValue(dbus.property(propertyName))
+}
+
+func (dbus *DBus) setProperty(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string, value *glib.Variant) bool {
+	if propertyName != "Paused" {
+		return false
+	}
+	clipboard.setPaused(value.Boolean())
+	return true
+}
+
+func (dbus *DBus) property(propertyName string) *glib.Variant {
+	switch propertyName {
+	case "Paused":
+		return glib.NewVariantBoolean(clipboard.paused)
+	default:
+		clipboard.count()
+		return glib.NewVariantUint32(uint32(clipboard.itemCount))
+	}
+}
+
+func (dbus *DBus) itemAdded(item ClipboardItem) {
+	if dbus.connection == nil {
+		return
+	}
+
+	err := dbus.connection.EmitSignal("", dbus.objectPath, dbusInterface, "ItemAdded", glib.NewVariantTuple([]*glib.Variant{dbusItem(item, false)}))
+	if err != nil {
+		log.Printf("Failed to emit D-Bus signal: %v", err)
+	}
+	dbus.countChanged()
+}
+
+func (dbus *DBus) countChanged() {
+	dbus.propertiesChanged("Count")
+}
+
+func (dbus *DBus) pausedChanged() {
+	dbus.propertiesChanged("Paused")
+}
+
+func (dbus *DBus) propertiesChanged(propertyNames ...string) {
+	if dbus.connection == nil {
+		return
+	}
+
+	var changed []*glib.Variant
+	for _, propertyName := range propertyNames {
+		changed = append(changed, glib.NewVariantDictEntry(glib.NewVariantString(propertyName), glib.NewVariantVariant(dbus.property(propertyName))))
+	}
+
+	parameters := glib.NewVariantTuple([]*glib.Variant{
+		glib.NewVariantString(dbusInterface),
+		glib.NewVariantArray(glib.NewVariantType("{sv}"), changed),
+		glib.NewVariantArray(glib.NewVariantType("s"), nil),
+	})
+	err := dbus.connection.EmitSignal("", dbus.objectPath, "org.freedesktop.DBus.Properties", "PropertiesChanged", parameters)
+	if err != nil {
+		log.Printf("Failed to emit D-Bus signal: %v", err)
+	}
+}
+
+func dbusLimit(limit uint32) int {
+	if limit == 0 {
+		return dbusDefaultLimit
+	}
+	return int(limit)
+}
+
+func dbusItems(items []ClipboardItem, withImages bool) *glib.Variant {
+	children := make([]*glib.Variant, len(items))
+	for i, item := range items {
+		children[i] = dbusItem(item, withImages)
+	}
+	return glib.NewVariantArray(glib.NewVariantType("(xsssss)"), children)
+}
+
+func dbusItem(item ClipboardItem, withImage bool) *glib.Variant {
+	content := item.content
+	if item.itemType == 2 && !withImage {
+		content = ""
+	}
+	return glib.NewVariantTuple([]*glib.Variant{
+		glib.NewVariantInt64(int64(item.id)),
+		glib.NewVariantString(itemTypeName(item.itemType)),
+		glib.NewVariantString(item.kind),
+		glib.NewVariantString(item.language),
+		glib.NewVariantString(item.dateTime),
+		glib.NewVariantString(content),
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const testObjectPath = "/bio/murat/clyp"
+
+// testDatabase opens an empty history in a temporary data directory.
+func testDatabase(t *testing.T) {
+	t.Helper()
+	app.dataDir = t.TempDir()
+	if err := database.init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.db.Close() })
+}
+
+// testBus starts a private session bus and returns a service and a client
+// connection to it.
+func testBus(t *testing.T) (*gio.DBusConnection, *gio.DBusConnection) {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	bus := gio.NewTestDBus(gio.TestDBusNone)
+	bus.Up()
+	t.Cleanup(bus.Down)
+
+	connect := func() *gio.DBusConnection {
+		connection, err := gio.NewDBusConnectionForAddressSync(context.Background(), bus.BusAddress(), gio.DBusConnectionFlagsAuthenticationClient|gio.DBusConnectionFlagsMessageBusConnection, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { connection.CloseSync(context.Background()) })
+		return connection
+	}
+	return connect(), connect()
+}
+
+// testCall calls a method and runs the default main context, where the
+// service handles it, until the reply arrives.
+func testCall(connection *gio.DBusConnection, busName, interfaceName, method string, parameters *glib.Variant) (*glib.Variant, error) {
+	var result *glib.Variant
+	var err error
+	done := false
+	connection.Call(context.Background(), busName, testObjectPath, interfaceName, method, parameters, nil, gio.DBusCallFlagsNone, 5000, func(res gio.AsyncResulter) {
+		result, err = connection.CallFinish(res)
+		done = true
+	})
+	for !done {
+		glib.MainContextDefault().Iteration(true)
+	}
+	return result, err
+}
+
+func TestDBusDelete(t *testing.T) {
+	testDatabase(t)
+	service, client := testBus(t)
+	if err := dbus.register(service, testObjectPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbus = DBus{} })
+
+	item, err := clipboard.insertItem(HookEntry{content: "hello", itemType: 1, kind: kindText})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := api.subscribe()
+	defer api.unsubscribe(events)
+
+	_, err = testCall(client, service.UniqueName(), dbusInterface, "Delete", glib.NewVariantTuple([]*glib.Variant{glib.NewVariantInt64(int64(item.id))}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := clipboard.item(strconv.Itoa(item.id)); err == nil {
+		t.Error("deleted item is still in the history")
+	}
+	select {
+	case event := <-events:
+		if event.Type != "item_deleted" {
+			t.Errorf("got a %q event, want item_deleted", event.Type)
+		}
+	default:
+		t.Error("no item_deleted event was published")
+	}
+
+	_, err = testCall(client, service.UniqueName(), dbusInterface, "Delete", glib.NewVariantTuple([]*glib.Variant{glib.NewVariantInt64(int64(item.id))}))
+	if err == nil {
+		t.Error("deleting a missing item succeeded")
+	}
+}
+
+func TestDBusCountProperty(t *testing.T) {
+	testDatabase(t)
+	service, client := testBus(t)
+	if err := dbus.register(service, testObjectPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbus = DBus{} })
+
+	for _, content := range []string{"one", "two"} {
+		if _, err := clipboard.insertItem(HookEntry{content: content, itemType: 1, kind: kindText}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := testCall(client, service.UniqueName(), "org.freedesktop.DBus.Properties", "Get", glib.NewVariantTuple([]*glib.Variant{glib.NewVariantString(dbusInterface), glib.NewVariantString("Count")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := result.ChildValue(0).Variant().Uint32(); count != 2 {
+		t.Errorf("Count is %d, want 2", count)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const exportSchemaVersion = 1
+
+var exportFormats = map[string]string{
+	"json":   ".json",
+	"ndjson": ".ndjson",
+	"csv":    ".csv",
+	"md":     ".md",
+}
+
+type Export struct {
+	format   string
+	imageDir string
+}
+
+type ExportDocument struct {
+	Version    int          `json:"version"`
+	Generator  string       `json:"generator"`
+	ExportedAt string       `json:"exported_at"`
+	Items      []ExportItem `json:"items"`
+}
+
+type ExportItem struct {
+	ID        int      `json:"id"`
+	Type      string   `json:"type"`
+	Kind      string   `json:"kind"`
+	Language  string   `json:"language,omitempty"`
+	DateTime  string   `json:"date_time"`
+	Content   string   `json:"content,omitempty"`
+	Image     string   `json:"image,omitempty"`
+	ImageFile string   `json:"image_file,omitempty"`
+	Tags      []string `json:"tags"`
+	Pinned    bool     `json:"pinned,omitempty"`
+	Source    string   `json:"source_app,omitempty"`
+}
+
+func exportFormatFromPath(path string) (string, bool) {
+	extension := strings.ToLower(filepath.Ext(path))
+	for format, formatExtension := range exportFormats {
+		if formatExtension == extension {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+func (export *Export) write(writer io.Writer, items []ClipboardItem) error {
+	exportItems := make([]ExportItem, 0, len(items))
+	for _, item := range items {
+		exportItem, err := export.item(item)
+		if err != nil {
+			return err
+		}
+		exportItems = append(exportItems, exportItem)
+	}
+
+	switch export.format {
+	case "json":
+		return export.writeJSON(writer, exportItems)
+	case "ndjson":
+		return export.writeNDJSON(writer, exportItems)
+	case "csv":
+		return export.writeCSV(writer, exportItems)
+	case "md":
+		return export.writeMarkdown(writer, exportItems)
+	default:
+		return fmt.Errorf("unknown export format %q", export.format)
+	}
+}
+
+func (export *Export) item(item ClipboardItem) (ExportItem, error) {
+	tags, err := clipboard.tags(item.id)
+	if err != nil {
+		return ExportItem{}, err
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	exportItem := ExportItem{
+		ID:       item.id,
+		Type:     itemTypeName(item.itemType),
+		Kind:     item.kind,
+		Language: item.language,
+		DateTime: exportDateTime(item.dateTime),
+		Tags:     tags,
+		Pinned:   item.pinned,
+		Source:   item.sourceApp,
+	}
+
+	if item.itemType != 2 {
+		exportItem.Content = item.content
+		return exportItem, nil
+	}
+
+	if export.imageDir == "" {
+		exportItem.Image = item.content
+		return exportItem, nil
+	}
+
+	imageData, err := base64.StdEncoding.DecodeString(item.content)
+	if err != nil {
+		return ExportItem{}, fmt.Errorf("item %d: %v", item.id, err)
+	}
+	if err := os.MkdirAll(export.imageDir, 0755); err != nil {
+		return ExportItem{}, err
+	}
+	imageName := strconv.Itoa(item.id) + imageExtension(imageData)
+	if err := os.WriteFile(filepath.Join(export.imageDir, imageName), imageData, 0644); err != nil {
+		return ExportItem{}, err
+	}
+	exportItem.ImageFile = filepath.Base(export.imageDir) + "/" + imageName
+
+	return exportItem, nil
+}
+
+func exportDateTime(dateTime string) string {
+	parsed, err := time.ParseInLocation(time.DateTime, dateTime, time.UTC)
+	if err != nil {
+		return dateTime
+	}
+	return parsed.Format(time.RFC3339)
+}
+
+func (export *Export) writeJSON(writer io.Writer, items []ExportItem) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ExportDocument{
+		Version:    exportSchemaVersion,
+		Generator:  app.id,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Items:      items,
+	})
+}
+
+func (export *Export) writeNDJSON(writer io.Writer, items []ExportItem) error {
+	encoder := json.NewEncoder(writer)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (export *Export) writeCSV(writer io.Writer, items []ExportItem) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write([]string{"id", "type", "kind", "language", "date_time", "content", "image", "image_file", "tags", "pinned", "source_app"})
+	for _, item := range items {
+		csvWriter.Write([]string{
+			strconv.Itoa(item.ID),
+			item.Type,
+			item.Kind,
+			item.Language,
+			item.DateTime,
+			item.Content,
+			item.Image,
+			item.ImageFile,
+			strings.Join(item.Tags, ","),
+			strconv.FormatBool(item.Pinned),
+			item.Source,
+		})
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func (export *Export) writeMarkdown(writer io.Writer, items []ExportItem) error {
+	var builder strings.Builder
+
+	builder.WriteString("# Clyp Export\n")
+	for _, item := range items {
+		fmt.Fprintf(&builder, "\n## %d · %s\n\n", item.ID, item.DateTime)
+		if len(item.Tags) > 0 {
+			fmt.Fprintf(&builder, "Tags: %s\n\n", strings.Join(item.Tags, ", "))
+		}
+		switch {
+		case item.ImageFile != "":
+			fmt.Fprintf(&builder, "![Image %d](%s)\n", item.ID, item.ImageFile)
+		case item.Image != "":
+			fmt.Fprintf(&builder, "![Image %d](data:%s;base64,%s)\n", item.ID, encodedImageMIMEType(item.Image), item.Image)
+		default:
+			fence := markdownFence(item.Content)
+			fmt.Fprintf(&builder, "%s%s\n%s\n%s\n", fence, item.Language, item.Content, fence)
+		}
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func markdownFence(content string) string {
+	longest := 0
+	current := 0
+	for _, char := range content {
+		if char == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"encoding/base64"
 	"log"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
@@ -27,9 +29,18 @@ var (
 type GUI struct {
 	clipboardItemsList *gtk.ListBox
 	searchEntry        *gtk.SearchEntry
+	searchErrorLabel   *gtk.Label
 	searchBar          *gtk.SearchBar
 	searchToggleButton *gtk.ToggleButton
 	window             *gtk.ApplicationWindow
+	mainStack          *gtk.Stack
+	snippetList        *gtk.ListBox
+	addSnippetButton   *gtk.Button
+	trashList          *gtk.ListBox
+	emptyTrashButton   *gtk.Button
+	itemContextMenu    *gtk.PopoverMenu
+	actionPalette      *gtk.Popover
+	itemActions        ActionRegistry
 }
 
 func (gui *GUI) init() {
@@ -54,16 +65,27 @@ func (gui *GUI) init() {
 }
 
 func (gui *GUI) activate(gtkApp *gtk.Application) {
+	if gui.window != nil {
+		gui.window.Present()
+		return
+	}
 	app.setupDataDir()
 	if err := database.init(); err != nil {
 		panic(err.Error())
 	}
 	builder := gtk.NewBuilderFromString(uiXML)
 	gui.window = builder.GetObject("gtk_window").Cast().(*gtk.ApplicationWindow)
+	notifier.init(gtkApp, gui.window.Present)
 	gui.clipboardItemsList = builder.GetObject("clipboard_list").Cast().(*gtk.ListBox)
 	gui.searchEntry = builder.GetObject("search_entry").Cast().(*gtk.SearchEntry)
+	gui.searchErrorLabel = builder.GetObject("search_error_label").Cast().(*gtk.Label)
 	gui.searchBar = builder.GetObject("search_bar").Cast().(*gtk.SearchBar)
 	gui.searchToggleButton = builder.GetObject("search_toggle_button").Cast().(*gtk.ToggleButton)
+	gui.mainStack = builder.GetObject("main_stack").Cast().(*gtk.Stack)
+	gui.snippetList = builder.GetObject("snippet_list").Cast().(*gtk.ListBox)
+	gui.addSnippetButton = builder.GetObject("add_snippet_button").Cast().(*gtk.Button)
+	gui.trashList = builder.GetObject("trash_list").Cast().(*gtk.ListBox)
+	gui.emptyTrashButton = builder.GetObject("empty_trash_button").Cast().(*gtk.Button)
 	gui.setupCSS()
 	glib.IdleAdd(func() {
 		gui.updateClipboardRows(true)
@@ -72,6 +94,13 @@ func (gui *GUI) activate(gtkApp *gtk.Application) {
 	gui.setupEvents(gtkApp)
 	gui.setupShortcutsAction(gtkApp)
 	gui.setupAboutAction(gtkApp)
+	gui.setupExportAction(gtkApp)
+	gui.setupSortAction(gtkApp)
+	gui.setupSnippets(gtkApp)
+	gui.setupTrash(gtkApp)
+	gui.setupClearAction(gtkApp)
+	gui.setupPreferencesAction(gtkApp)
+	gui.setupItemActions(gtkApp)
 	gui.setupActionRunOnStartup(gtkApp)
 	gui.setupStyleSupport()
 	gui.window.SetApplication(gtkApp)
@@ -134,11 +163,38 @@ func (gui *GUI) updateClipboardRows(updateItemCount bool) {
 }
 
 func (gui *GUI) addTextRow(item ClipboardItem) {
+	rowBox := gtk.NewBox(gtk.OrientationHorizontal, 12)
+	rowBox.SetMarginStart(12)
+	rowBox.SetMarginEnd(12)
+
+	var swatch *gtk.DrawingArea
+	if item.kind == kindColor {
+		swatch = newColorSwatch(strings.TrimSpace(item.content), 16)
+	}
+	if swatch != nil {
+		swatch.SetVAlign(gtk.AlignStart)
+		swatch.SetMarginTop(14)
+		rowBox.Append(swatch)
+	} else {
+		kindIcon := gtk.NewImageFromIconName(kindIcons[item.kind])
+		kindIcon.SetTooltipText(item.kind)
+		kindIcon.SetVAlign(gtk.AlignStart)
+		kindIcon.SetMarginTop(14)
+		kindIcon.AddCSSClass("item-kind")
+		rowBox.Append(kindIcon)
+	}
+
+	var quickActions []ItemAction
+	for _, action := range gui.itemActions.forItem(item) {
+		if action.quick {
+			quickActions = append(quickActions, action)
+		}
+	}
+
 	box := gtk.NewBox(gtk.OrientationVertical, 6)
 	box.SetMarginTop(12)
 	box.SetMarginBottom(12)
-	box.SetMarginStart(12)
-	box.SetMarginEnd(12)
+	box.SetHExpand(true)
 	box.AddCSSClass("item-box")
 
 	if len(item.content) > 100 {
@@ -150,17 +206,48 @@ func (gui *GUI) addTextRow(item ClipboardItem) {
 	contentLabel.SetXAlign(0)
 	contentLabel.AddCSSClass("title")
 
-	dateLabel := gtk.NewLabel(item.dateTime)
+	subtitle := item.dateTime
+	if item.language != "" {
+		subtitle += " · " + item.language
+	}
+	if item.sourceApp != "" {
+		subtitle += " · " + item.sourceApp
+	}
+	if item.copyCount > 0 {
+		subtitle += " · Copied " + strconv.Itoa(item.copyCount) + "×"
+	}
+	if item.pinned {
+		subtitle += " · Pinned"
+	}
+	dateLabel := gtk.NewLabel(subtitle)
 	dateLabel.SetXAlign(0)
 	dateLabel.AddCSSClass("subtitle")
+	if item.sourceTitle != "" {
+		dateLabel.SetTooltipText(item.sourceTitle)
+	}
 
 	box.Append(contentLabel)
 	box.Append(dateLabel)
+	rowBox.Append(box)
+
+	for _, action := range quickActions {
+		button := gtk.NewButtonFromIconName(action.icon)
+		button.SetTooltipText(action.label)
+		button.SetHasFrame(false)
+		button.SetVAlign(gtk.AlignCenter)
+		button.SetCanFocus(false)
+		button.ConnectClicked(func() {
+			if err := action.run(item); err != nil {
+				gui.showToast(err.Error())
+			}
+		})
+		rowBox.Append(button)
+	}
 
 	row := gtk.NewListBoxRow()
 	row.SetName(strconv.Itoa(item.id))
 	row.AddCSSClass("item-row")
-	row.SetChild(box)
+	row.SetChild(rowBox)
 
 	gui.clipboardItemsList.Append(row)
 }
@@ -193,9 +280,22 @@ func (gui *GUI) addImageRow(item ClipboardItem) {
 		}
 	}
 
-	dateLabel := gtk.NewLabel(item.dateTime)
+	subtitle := item.dateTime
+	if item.sourceApp != "" {
+		subtitle += " · " + item.sourceApp
+	}
+	if item.copyCount > 0 {
+		subtitle += " · Copied " + strconv.Itoa(item.copyCount) + "×"
+	}
+	if item.pinned {
+		subtitle += " · Pinned"
+	}
+	dateLabel := gtk.NewLabel(subtitle)
 	dateLabel.SetXAlign(0)
 	dateLabel.AddCSSClass("subtitle")
+	if item.sourceTitle != "" {
+		dateLabel.SetTooltipText(item.sourceTitle)
+	}
 	box.Append(dateLabel)
 
 	row := gtk.NewListBoxRow()
@@ -258,26 +358,29 @@ func (gui *GUI) setupClipBoardListEvents() {
 
 	clipboardListkeyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
 		if keyval == gdk.KEY_Return || keyval == gdk.KEY_KP_Enter {
-			selectedRow := gui.clipboardItemsList.SelectedRow()
-			if selectedRow != nil {
-				gui.closeSearchBar()
-				clipboard.copy(selectedRow.Name())
-				glib.IdleAdd(func() {
-					gui.updateClipboardRows(true)
-					gui.focusFirstClipboardListItem()
-				})
+			if gui.clipboardItemsList.SelectedRow() != nil {
+				gui.copySelectedItem()
+				return true
+			}
+		}
+
+		if keyval == gdk.KEY_Menu || (keyval == gdk.KEY_F10 && state&gdk.ShiftMask != 0) {
+			if gui.clipboardItemsList.SelectedRow() != nil {
+				gui.showItemContextMenuForSelectedRow()
+				return true
+			}
+		}
+
+		if state&gdk.ControlMask != 0 && keyval == gdk.KEY_k {
+			if gui.clipboardItemsList.SelectedRow() != nil {
+				gui.showActionPalette()
 				return true
 			}
 		}
 
 		if keyval == gdk.KEY_Delete {
-			selectedRow := gui.clipboardItemsList.SelectedRow()
-			if selectedRow != nil {
-				clipboard.removeFromDatabase(selectedRow.Name())
-				glib.IdleAdd(func() {
-					gui.updateClipboardRows(true)
-					gui.focusFirstClipboardListItem()
-				})
+			if gui.clipboardItemsList.SelectedRow() != nil {
+				gui.deleteSelectedItems()
 				return true
 			}
 		}
@@ -293,22 +396,43 @@ func (gui *GUI) setupClipBoardListEvents() {
 	gestureClick := gtk.NewGestureClick()
 
 	gestureClick.ConnectPressed(func(nPress int, x, y float64) {
-		if nPress == 2 {
-			selectedRow := gui.clipboardItemsList.SelectedRow()
-			if selectedRow != nil {
-				gui.closeSearchBar()
-				clipboard.copy(selectedRow.Name())
-				glib.IdleAdd(func() {
-					gui.updateClipboardRows(true)
-					gui.focusFirstClipboardListItem()
-				})
-			}
+		if nPress == 2 && gui.clipboardItemsList.SelectedRow() != nil {
+			gui.copySelectedItem()
 		}
 	})
 
 	gui.clipboardItemsList.AddController(clipboardListkeyController)
 	gui.clipboardItemsList.AddController(gestureClick)
+	gui.setupItemContextMenu()
+
+}
 
+func (gui *GUI) copySelectedItem(transformNames ...string) {
+	selectedRow := gui.clipboardItemsList.SelectedRow()
+	if selectedRow == nil {
+		return
+	}
+
+	gui.closeSearchBar()
+	if err := clipboard.copy(selectedRow.Name(), transformNames...); err != nil {
+		gui.showToast(err.Error())
+		return
+	}
+	// The window is hidden so the keystroke reaches the previous window, and
+	// shown again once the paste was sent.
+	hidden := false
+	autoPaste(func() {
+		hidden = true
+		gui.window.SetVisible(false)
+	}, func() {
+		glib.IdleAdd(func() {
+			if hidden {
+				gui.window.SetVisible(true)
+			}
+			gui.updateClipboardRows(true)
+			gui.focusFirstClipboardListItem()
+		})
+	})
 }
 
 func (gui *GUI) setupWindowEvents() {
@@ -343,6 +467,7 @@ func (gui *GUI) setupSearchBarEvents() {
 	gui.searchEntry.ConnectSearchChanged(func() {
 		if gui.searchEntry.Text() == "" {
 			database.searchFilter = ""
+			gui.showSearchError(nil)
 			glib.IdleAdd(func() {
 				gui.updateClipboardRows(true)
 				gui.focusFirstClipboardListItem()
@@ -350,6 +475,11 @@ func (gui *GUI) setupSearchBarEvents() {
 			gui.closeSearchBar()
 			return
 		}
+		if err := validateSearchFilter(gui.searchEntry.Text()); err != nil {
+			gui.showSearchError(err)
+			return
+		}
+		gui.showSearchError(nil)
 		database.searchFilter = gui.searchEntry.Text()
 		glib.IdleAdd(func() {
 			gui.updateClipboardRows(true)
@@ -378,6 +508,17 @@ func (gui *GUI) setupSearchBarEvents() {
 	gui.searchEntry.AddController(searchEntryKeyController)
 }
 
+func (gui *GUI) showSearchError(err error) {
+	if err == nil {
+		gui.searchEntry.RemoveCSSClass("error")
+		gui.searchErrorLabel.SetVisible(false)
+		return
+	}
+	gui.searchEntry.AddCSSClass("error")
+	gui.searchErrorLabel.SetText(err.Error())
+	gui.searchErrorLabel.SetVisible(true)
+}
+
 func (gui *GUI) focusFirstClipboardListItem() {
 	if gui.clipboardItemsList.RowAtIndex(0) == nil {
 		return
@@ -508,6 +649,14 @@ func (gui *GUI) hasStartupEntry() bool {
 }
 
 func (gui *GUI) showAddToStartupToast() {
+	gui.showToast("Go the menu to add Clyp to the system startup.")
+}
+
+func (gui *GUI) showToast(message string) {
+	gui.showActionToast(message, "", nil)
+}
+
+func (gui *GUI) showActionToast(message, actionLabel string, action func()) {
 	revealer := gtk.NewRevealer()
 	revealer.SetTransitionType(gtk.RevealerTransitionTypeSlideDown)
 	revealer.SetTransitionDuration(300)
@@ -520,10 +669,18 @@ func (gui *GUI) showAddToStartupToast() {
 	toastBox.SetMarginEnd(20)
 	toastBox.AddCSSClass("toast")
 
-	label := gtk.NewLabel("Go the menu to add Clyp to the system startup.")
+	label := gtk.NewLabel(message)
 	label.SetHAlign(gtk.AlignCenter)
 	toastBox.Append(label)
 
+	timeout := uint(3000)
+	var actionButton *gtk.Button
+	if action != nil {
+		timeout = 6000
+		actionButton = gtk.NewButtonWithLabel(actionLabel)
+		toastBox.Append(actionButton)
+	}
+
 	closeButton := gtk.NewButtonFromIconName("window-close-symbolic")
 	closeButton.SetHasFrame(false)
 	toastBox.Append(closeButton)
@@ -535,20 +692,98 @@ func (gui *GUI) showAddToStartupToast() {
 
 	revealer.SetRevealChild(true)
 
-	glib.TimeoutAdd(3000, func() bool {
+	hidden := false
+	hide := func() {
+		if hidden {
+			return
+		}
+		hidden = true
 		revealer.SetRevealChild(false)
 		glib.TimeoutAdd(300, func() bool {
 			mainBox.Remove(revealer)
 			return false
 		})
+	}
+
+	glib.TimeoutAdd(timeout, func() bool {
+		hide()
 		return false
 	})
 
-	closeButton.ConnectClicked(func() {
-		revealer.SetRevealChild(false)
-		glib.TimeoutAdd(300, func() bool {
-			mainBox.Remove(revealer)
-			return false
+	closeButton.ConnectClicked(hide)
+
+	if actionButton != nil {
+		actionButton.ConnectClicked(func() {
+			hide()
+			action()
 		})
+	}
+}
+
+func (gui *GUI) setupSortAction(gtkApp *gtk.Application) {
+	sortAction := gio.NewSimpleActionStateful("sort_mode", glib.NewVariantType("s"), glib.NewVariantString(config.SortMode))
+	sortAction.ConnectActivate(func(parameter *glib.Variant) {
+		sortAction.SetState(parameter)
+		config.load()
+		config.SortMode = parameter.String()
+		if err := config.save(); err != nil {
+			log.Printf("Failed to save sort mode: %v", err)
+		}
+		gui.updateClipboardRows(false)
+		gui.focusFirstClipboardListItem()
+	})
+	gtkApp.AddAction(sortAction)
+}
+
+func (gui *GUI) setupExportAction(gtkApp *gtk.Application) {
+	exportAction := gio.NewSimpleAction("export", nil)
+	exportAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showExportDialog(ItemFilter{})
+	})
+	gtkApp.AddAction(exportAction)
+}
+
+func (gui *GUI) showExportDialog(filter ItemFilter) {
+	fileDialog := gtk.NewFileDialog()
+	fileDialog.SetTitle("Export History")
+	fileDialog.SetInitialName("clyp-export.json")
+	fileDialog.Save(context.Background(), &gui.window.Window, func(result gio.AsyncResulter) {
+		file, err := fileDialog.SaveFinish(result)
+		if err != nil || file == nil {
+			return
+		}
+		count, err := gui.exportToFile(file.Path(), filter)
+		if err != nil {
+			log.Printf("Failed to export history: %v", err)
+			gui.showToast("Export failed: " + err.Error())
+			return
+		}
+		gui.showToast("Exported " + strconv.Itoa(count) + " items.")
 	})
 }
+
+func (gui *GUI) exportToFile(path string, filter ItemFilter) (int, error) {
+	format, ok := exportFormatFromPath(path)
+	if !ok {
+		format = "json"
+		path += exportFormats[format]
+	}
+
+	items, err := clipboard.filteredItems(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	export := Export{format: format}
+	if err := export.write(file, items); err != nil {
+		return 0, err
+	}
+
+	return len(items), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+var clearAges = []struct {
+	label     string
+	olderThan string
+}{
+	{"All items", ""},
+	{"Older than a day", "1d"},
+	{"Older than a week", "7d"},
+	{"Older than a month", "30d"},
+}
+
+func (gui *GUI) setupClearAction(gtkApp *gtk.Application) {
+	clearHistoryAction := gio.NewSimpleAction("clear_history", nil)
+	clearHistoryAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showClearDialog()
+	})
+	gtkApp.AddAction(clearHistoryAction)
+}
+
+func (gui *GUI) showClearDialog() {
+	dialog, box := gui.newDialog("Clear History")
+
+	var ageLabels []string
+	for _, age := range clearAges {
+		ageLabels = append(ageLabels, age.label)
+	}
+	ageDropDown := gtk.NewDropDownFromStrings(ageLabels)
+	typeDropDown := gtk.NewDropDownFromStrings([]string{"Texts and images", "Only texts", "Only images"})
+	keepPinnedButton := gtk.NewCheckButtonWithLabel("Keep pinned items")
+	keepPinnedButton.SetActive(true)
+
+	summaryLabel := gtk.NewLabel("")
+	summaryLabel.SetWrap(true)
+	summaryLabel.SetXAlign(0)
+
+	filter := func() ItemFilter {
+		filter := ItemFilter{
+			itemType: byte(typeDropDown.Selected()),
+			anyState: true,
+			unpinned: keepPinnedButton.Active(),
+		}
+		if olderThan := clearAges[ageDropDown.Selected()].olderThan; olderThan != "" {
+			filter.until, _ = parseTimeValue(olderThan)
+		}
+		return filter
+	}
+
+	updateSummary := func() {
+		texts, images, err := clipboard.countItems(filter())
+		if err != nil {
+			summaryLabel.SetText(err.Error())
+			return
+		}
+		summaryLabel.SetText(fmt.Sprintf("%d texts and %d images will be deleted permanently, including items in the trash.", texts, images))
+	}
+	ageDropDown.NotifyProperty("selected", updateSummary)
+	typeDropDown.NotifyProperty("selected", updateSummary)
+	keepPinnedButton.ConnectToggled(updateSummary)
+	updateSummary()
+
+	buttons := gui.newDialogButtons(dialog, "Clear", func() {
+		count, err := clipboard.clear(filter())
+		dialog.Close()
+		if err != nil {
+			log.Printf("Failed to clear history: %v", err)
+			gui.showToast("Clearing history failed: " + err.Error())
+			return
+		}
+		gui.showToast(fmt.Sprintf("Deleted %d items.", count))
+		gui.updateTrashRows()
+	})
+
+	box.Append(ageDropDown)
+	box.Append(typeDropDown)
+	box.Append(keepPinnedButton)
+	box.Append(summaryLabel)
+	box.Append(buttons)
+
+	dialog.SetVisible(true)
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+func (gui *GUI) setupItemActions(gtkApp *gtk.Application) {
+	copyItemAction := gio.NewSimpleAction("copy_item", nil)
+	copyItemAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.copySelectedItem()
+	})
+	gtkApp.AddAction(copyItemAction)
+
+	copyAsAction := gio.NewSimpleAction("copy_as", glib.NewVariantType("s"))
+	copyAsAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.copySelectedItem(parameter.String())
+	})
+	gtkApp.AddAction(copyAsAction)
+
+	togglePinAction := gio.NewSimpleAction("toggle_pin", nil)
+	togglePinAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.togglePinSelectedItem()
+	})
+	gtkApp.AddAction(togglePinAction)
+
+	itemAction := gio.NewSimpleAction("item_action", glib.NewVariantType("s"))
+	itemAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.runItemAction(parameter.String())
+	})
+	gtkApp.AddAction(itemAction)
+
+	gui.setupSelectionActions(gtkApp)
+	gui.registerItemActions()
+}
+
+func (gui *GUI) togglePinSelectedItem() {
+	selectedRow := gui.clipboardItemsList.SelectedRow()
+	if selectedRow == nil {
+		return
+	}
+
+	item, err := clipboard.item(selectedRow.Name())
+	if err != nil {
+		log.Printf("Failed to get item: %v", err)
+		return
+	}
+
+	if err := clipboard.setPinned(selectedRow.Name(), !item.pinned); err != nil {
+		log.Printf("Failed to pin item: %v", err)
+		return
+	}
+
+	index := selectedRow.Index()
+	gui.updateClipboardRows(false)
+	if row := gui.clipboardItemsList.RowAtIndex(index); row != nil {
+		gui.clipboardItemsList.SelectRow(row)
+		row.GrabFocus()
+	}
+}
+
+func (gui *GUI) setupItemContextMenu() {
+	gui.itemContextMenu = gtk.NewPopoverMenuFromModel(nil)
+	gui.itemContextMenu.SetParent(gui.clipboardItemsList)
+	gui.itemContextMenu.SetHasArrow(false)
+
+	rightClick := gtk.NewGestureClick()
+	rightClick.SetButton(gdk.BUTTON_SECONDARY)
+	rightClick.ConnectPressed(func(nPress int, x, y float64) {
+		row := gui.clipboardItemsList.RowAtY(int(y))
+		if row == nil {
+			return
+		}
+		if !row.IsSelected() {
+			gui.clipboardItemsList.UnselectAll()
+			gui.clipboardItemsList.SelectRow(row)
+		}
+		gui.showItemContextMenu(int(x), int(y))
+	})
+	gui.clipboardItemsList.AddController(rightClick)
+}
+
+func (gui *GUI) showItemContextMenuForSelectedRow() {
+	selectedRow := gui.clipboardItemsList.SelectedRow()
+	bounds, ok := selectedRow.ComputeBounds(gui.clipboardItemsList)
+	if !ok {
+		return
+	}
+	gui.showItemContextMenu(int(bounds.X()+bounds.Width()/2), int(bounds.Y()+bounds.Height()/2))
+}
+
+func (gui *GUI) showItemContextMenu(x, y int) {
+	selectedRow := gui.clipboardItemsList.SelectedRow()
+	if selectedRow == nil {
+		return
+	}
+
+	if selectedRows := gui.clipboardItemsList.SelectedRows(); len(selectedRows) > 1 {
+		gui.itemContextMenu.SetMenuModel(gui.selectionMenuModel(len(selectedRows)))
+	} else {
+		item, err := clipboard.item(selectedRow.Name())
+		if err != nil {
+			log.Printf("Failed to get item: %v", err)
+			return
+		}
+		gui.itemContextMenu.SetMenuModel(gui.itemMenuModel(item))
+	}
+	rect := gdk.NewRectangle(x, y, 1, 1)
+	gui.itemContextMenu.SetPointingTo(&rect)
+	gui.itemContextMenu.Popup()
+}
+
+func (gui *GUI) itemMenuModel(item ClipboardItem) *gio.Menu {
+	menu := gio.NewMenu()
+	menu.Append("Copy", "app.copy_item")
+	if item.pinned {
+		menu.Append("Unpin", "app.toggle_pin")
+	} else {
+		menu.Append("Pin", "app.toggle_pin")
+	}
+
+	if actions := gui.itemActions.forItem(item); len(actions) > 0 {
+		actionMenu := gio.NewMenu()
+		for _, action := range actions {
+			menuItem := gio.NewMenuItem(action.label, "")
+			menuItem.SetActionAndTargetValue("app.item_action", glib.NewVariantString(action.id))
+			actionMenu.AppendItem(menuItem)
+		}
+		menu.AppendSection("", actionMenu)
+	}
+
+	if item.itemType == 1 {
+		transformMenu := gio.NewMenu()
+		for _, transform := range transforms {
+			menuItem := gio.NewMenuItem(transform.label, "")
+			menuItem.SetActionAndTargetValue("app.copy_as", glib.NewVariantString(transform.name))
+			transformMenu.AppendItem(menuItem)
+		}
+		menu.AppendSubmenu("Copy As", transformMenu)
+	}
+
+	return menu
+}
+
+type paletteEntry struct {
+	label    string
+	activate func()
+}
+
+func (gui *GUI) paletteEntries(item ClipboardItem) []paletteEntry {
+	entries := []paletteEntry{{label: "Copy", activate: func() { gui.copySelectedItem() }}}
+
+	for _, action := range gui.itemActions.forItem(item) {
+		entries = append(entries, paletteEntry{label: action.label, activate: func() {
+			gui.runItemAction(action.id)
+		}})
+	}
+
+	if item.itemType == 1 {
+		for _, transform := range transforms {
+			entries = append(entries, paletteEntry{label: "Copy As " + transform.label, activate: func() {
+				gui.copySelectedItem(transform.name)
+			}})
+		}
+	}
+
+	return entries
+}
+
+func (gui *GUI) showActionPalette() {
+	selectedRow := gui.clipboardItemsList.SelectedRow()
+	if selectedRow == nil {
+		return
+	}
+
+	item, err := clipboard.item(selectedRow.Name())
+	if err != nil {
+		log.Printf("Failed to get item: %v", err)
+		return
+	}
+
+	entries := gui.paletteEntries(item)
+
+	if gui.actionPalette != nil {
+		gui.actionPalette.Unparent()
+	}
+	gui.actionPalette = gtk.NewPopover()
+	gui.actionPalette.SetParent(selectedRow)
+	gui.actionPalette.SetPosition(gtk.PosBottom)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	searchEntry := gtk.NewSearchEntry()
+	searchEntry.SetPlaceholderText("Search actions")
+	box.Append(searchEntry)
+
+	list := gtk.NewListBox()
+	list.SetSelectionMode(gtk.SelectionBrowse)
+	for i, entry := range entries {
+		label := gtk.NewLabel(entry.label)
+		label.SetXAlign(0)
+		label.SetMarginTop(4)
+		label.SetMarginBottom(4)
+		label.SetMarginStart(6)
+		label.SetMarginEnd(6)
+		row := gtk.NewListBoxRow()
+		row.SetName(strconv.Itoa(i))
+		row.SetChild(label)
+		list.Append(row)
+	}
+
+	scrolledWindow := gtk.NewScrolledWindow()
+	scrolledWindow.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	scrolledWindow.SetMinContentHeight(240)
+	scrolledWindow.SetMinContentWidth(280)
+	scrolledWindow.SetChild(list)
+	box.Append(scrolledWindow)
+	gui.actionPalette.SetChild(box)
+
+	list.SetFilterFunc(func(row *gtk.ListBoxRow) bool {
+		index, _ := strconv.Atoi(row.Name())
+		return strings.Contains(strings.ToLower(entries[index].label), strings.ToLower(searchEntry.Text()))
+	})
+
+	activate := func(row *gtk.ListBoxRow) {
+		if row == nil {
+			return
+		}
+		index, _ := strconv.Atoi(row.Name())
+		gui.actionPalette.Popdown()
+		entries[index].activate()
+	}
+
+	selectFirstVisible := func() {
+		for i := 0; ; i++ {
+			row := list.RowAtIndex(i)
+			if row == nil {
+				list.UnselectAll()
+				return
+			}
+			if row.ChildVisible() {
+				list.SelectRow(row)
+				return
+			}
+		}
+	}
+
+	searchEntry.ConnectSearchChanged(func() {
+		list.InvalidateFilter()
+		selectFirstVisible()
+	})
+	searchEntry.ConnectActivate(func() {
+		activate(list.SelectedRow())
+	})
+	searchEntry.ConnectStopSearch(func() {
+		gui.actionPalette.Popdown()
+	})
+	list.ConnectRowActivated(activate)
+
+	searchEntryKeyController := gtk.NewEventControllerKey()
+	searchEntryKeyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		if keyval != gdk.KEY_Down && keyval != gdk.KEY_Up {
+			return false
+		}
+		selectedRow := list.SelectedRow()
+		if selectedRow == nil {
+			selectFirstVisible()
+			return true
+		}
+		step := 1
+		if keyval == gdk.KEY_Up {
+			step = -1
+		}
+		for index := selectedRow.Index() + step; ; index += step {
+			row := list.RowAtIndex(index)
+			if row == nil {
+				return true
+			}
+			if row.ChildVisible() {
+				list.SelectRow(row)
+				return true
+			}
+		}
+	})
+	searchEntry.AddController(searchEntryKeyController)
+
+	gui.actionPalette.ConnectClosed(func() {
+		gui.clipboardItemsList.GrabFocus()
+	})
+
+	selectFirstVisible()
+	gui.actionPalette.Popup()
+	searchEntry.GrabFocus()
+}
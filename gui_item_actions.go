@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+func (gui *GUI) registerItemActions() {
+	gui.itemActions.register(ItemAction{
+		id:    "open-url",
+		label: "Open in Browser",
+		icon:  "web-browser-symbolic",
+		kinds: []string{kindURL},
+		quick: true,
+		run: func(item ClipboardItem) error {
+			uri := strings.TrimSpace(item.content)
+			if strings.HasPrefix(uri, "www.") {
+				uri = "https://" + uri
+			}
+			gui.launchURI(uri)
+			return nil
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "compose-mail",
+		label: "Compose Mail",
+		icon:  "mail-send-symbolic",
+		kinds: []string{kindEmail},
+		quick: true,
+		run: func(item ClipboardItem) error {
+			gui.launchURI("mailto:" + strings.TrimPrefix(strings.TrimSpace(item.content), "mailto:"))
+			return nil
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "open-path",
+		label: "Open",
+		icon:  "document-open-symbolic",
+		kinds: []string{kindPath},
+		run: func(item ClipboardItem) error {
+			gui.launchURI(pathURI(strings.TrimSpace(item.content)))
+			return nil
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "show-in-file-manager",
+		label: "Show in File Manager",
+		icon:  "folder-open-symbolic",
+		kinds: []string{kindPath},
+		quick: true,
+		run: func(item ClipboardItem) error {
+			gui.showInFileManager(pathURI(strings.TrimSpace(item.content)))
+			return nil
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "call",
+		label: "Call",
+		icon:  "call-start-symbolic",
+		kinds: []string{kindPhone},
+		quick: true,
+		run: func(item ClipboardItem) error {
+			gui.launchURI("tel:" + strings.Join(strings.Fields(item.content), ""))
+			return nil
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "preview-color",
+		label: "Preview Color",
+		icon:  "applications-graphics-symbolic",
+		kinds: []string{kindColor},
+		quick: true,
+		run: func(item ClipboardItem) error {
+			return gui.showColorPreview(strings.TrimSpace(item.content))
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "validate-json",
+		label: "Validate JSON",
+		icon:  "emblem-ok-symbolic",
+		kinds: []string{kindJSON, kindCode, kindText},
+		run: func(item ClipboardItem) error {
+			var value any
+			if err := json.Unmarshal([]byte(item.content), &value); err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			gui.showToast("Valid JSON.")
+			return nil
+		},
+	})
+	gui.itemActions.register(ItemAction{
+		id:    "copy-pretty-json",
+		label: "Copy Pretty-Printed JSON",
+		icon:  "format-justify-left-symbolic",
+		kinds: []string{kindJSON},
+		quick: true,
+		run: func(item ClipboardItem) error {
+			return clipboard.copy(strconv.Itoa(item.id), "json-pretty")
+		},
+	})
+
+	gui.itemActions.registerCustomActions(config.Actions)
+}
+
+func (gui *GUI) runItemAction(id string) {
+	selectedRow := gui.clipboardItemsList.SelectedRow()
+	if selectedRow == nil {
+		return
+	}
+
+	action, ok := gui.itemActions.find(id)
+	if !ok {
+		return
+	}
+
+	item, err := clipboard.item(selectedRow.Name())
+	if err != nil {
+		log.Printf("Failed to get item: %v", err)
+		return
+	}
+
+	if err := action.run(item); err != nil {
+		gui.showToast(err.Error())
+	}
+}
+
+func pathURI(path string) string {
+	if strings.HasPrefix(path, "file://") {
+		return path
+	}
+	if relativePath, ok := strings.CutPrefix(path, "~/"); ok {
+		if userHomeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(userHomeDir, relativePath)
+		}
+	}
+	if absolutePath, err := filepath.Abs(path); err == nil {
+		path = absolutePath
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func (gui *GUI) launchURI(uri string) {
+	launcher := gtk.NewURILauncher(uri)
+	launcher.Launch(context.Background(), &gui.window.Window, func(result gio.AsyncResulter) {
+		if err := launcher.LaunchFinish(result); err != nil {
+			log.Printf("Failed to open %s: %v", uri, err)
+			gui.showToast("Could not open " + uri)
+		}
+	})
+}
+
+func (gui *GUI) showInFileManager(uri string) {
+	launcher := gtk.NewFileLauncher(gio.NewFileForURI(uri))
+	launcher.OpenContainingFolder(context.Background(), &gui.window.Window, func(result gio.AsyncResulter) {
+		if err := launcher.OpenContainingFolderFinish(result); err != nil {
+			log.Printf("Failed to show %s: %v", uri, err)
+			gui.showToast("Could not show " + uri)
+		}
+	})
+}
+
+func newColorSwatch(spec string, size int) *gtk.DrawingArea {
+	color := gdk.NewRGBA(0, 0, 0, 1)
+	if !color.Parse(spec) {
+		return nil
+	}
+
+	swatch := gtk.NewDrawingArea()
+	swatch.SetContentWidth(size)
+	swatch.SetContentHeight(size)
+	swatch.AddCSSClass("color-swatch")
+	swatch.SetDrawFunc(func(area *gtk.DrawingArea, cr *cairo.Context, width, height int) {
+		cr.SetSourceRGBA(float64(color.Red()), float64(color.Green()), float64(color.Blue()), float64(color.Alpha()))
+		cr.Rectangle(0, 0, float64(width), float64(height))
+		cr.Fill()
+	})
+
+	return swatch
+}
+
+func (gui *GUI) showColorPreview(spec string) error {
+	color := gdk.NewRGBA(0, 0, 0, 1)
+	if !color.Parse(spec) {
+		return fmt.Errorf("Unsupported color: %s", spec)
+	}
+
+	dialog, box := gui.newDialog(spec)
+	swatch := newColorSwatch(spec, 160)
+	swatch.SetHExpand(true)
+	box.Append(swatch)
+
+	hex := fmt.Sprintf("#%02x%02x%02x", colorByte(color.Red()), colorByte(color.Green()), colorByte(color.Blue()))
+	for _, value := range []string{hex, color.String()} {
+		valueBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+		valueLabel := gtk.NewLabel(value)
+		valueLabel.SetSelectable(true)
+		valueLabel.SetHExpand(true)
+		valueLabel.SetXAlign(0)
+		copyButton := gtk.NewButtonFromIconName("edit-copy-symbolic")
+		copyButton.SetTooltipText("Copy")
+		copyValue := value
+		copyButton.ConnectClicked(func() {
+			clipboard.setText(copyValue)
+			dialog.Close()
+		})
+		valueBox.Append(valueLabel)
+		valueBox.Append(copyButton)
+		box.Append(valueBox)
+	}
+
+	dialog.SetVisible(true)
+
+	return nil
+}
+
+func colorByte(value float32) int {
+	return int(value*255 + 0.5)
+}
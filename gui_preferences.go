@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+func (gui *GUI) setupPreferencesAction(gtkApp *gtk.Application) {
+	preferencesAction := gio.NewSimpleAction("preferences", nil)
+	preferencesAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showPreferencesDialog()
+	})
+	gtkApp.AddAction(preferencesAction)
+}
+
+func (gui *GUI) showPreferencesDialog() {
+	config.load()
+	dialog, box := gui.newDialog("Preferences")
+
+	headingLabel := gtk.NewLabel("Ignored Applications")
+	headingLabel.SetXAlign(0)
+	headingLabel.AddCSSClass("heading")
+
+	helpLabel := gtk.NewLabel("Copies from these applications are not recorded. Enter an application id or WM_CLASS, or a /regex/ that is also matched to window titles.")
+	helpLabel.SetWrap(true)
+	helpLabel.SetXAlign(0)
+	helpLabel.AddCSSClass("dim-label")
+
+	ignoredList := gtk.NewListBox()
+	ignoredList.SetSelectionMode(gtk.SelectionNone)
+	ignoredList.AddCSSClass("boxed-list")
+
+	ignoredScroll := gtk.NewScrolledWindow()
+	ignoredScroll.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	ignoredScroll.SetMinContentHeight(160)
+	ignoredScroll.SetVExpand(true)
+	ignoredScroll.SetChild(ignoredList)
+	ignoredScroll.AddCSSClass("frame")
+
+	errorLabel := gtk.NewLabel("")
+	errorLabel.SetVisible(false)
+	errorLabel.SetXAlign(0)
+	errorLabel.AddCSSClass("search-error")
+
+	showError := func(err error) {
+		errorLabel.SetVisible(err != nil)
+		if err != nil {
+			log.Printf("Failed to update ignored applications: %v", err)
+			errorLabel.SetText(err.Error())
+		}
+	}
+
+	var updateIgnoredRows func()
+	updateIgnoredRows = func() {
+		ignoredList.RemoveAll()
+		for _, rule := range config.IgnoredApps {
+			ruleLabel := gtk.NewLabel(rule)
+			ruleLabel.SetXAlign(0)
+			ruleLabel.SetHExpand(true)
+
+			removeButton := gtk.NewButtonFromIconName("list-remove-symbolic")
+			removeButton.SetTooltipText("Remove")
+			removeButton.SetHasFrame(false)
+			removeButton.ConnectClicked(func() {
+				showError(removeIgnoredApp(rule))
+				updateIgnoredRows()
+			})
+
+			rowBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+			rowBox.SetMarginTop(6)
+			rowBox.SetMarginBottom(6)
+			rowBox.SetMarginStart(12)
+			rowBox.SetMarginEnd(6)
+			rowBox.Append(ruleLabel)
+			rowBox.Append(removeButton)
+			ignoredList.Append(rowBox)
+		}
+	}
+	updateIgnoredRows()
+
+	ruleEntry := gtk.NewEntry()
+	ruleEntry.SetPlaceholderText("e.g. org.keepassxc.KeePassXC")
+	ruleEntry.SetHExpand(true)
+
+	addRule := func() {
+		if err := addIgnoredApp(ruleEntry.Text()); err != nil {
+			showError(err)
+			return
+		}
+		showError(nil)
+		ruleEntry.SetText("")
+		updateIgnoredRows()
+	}
+	ruleEntry.ConnectActivate(addRule)
+
+	addButton := gtk.NewButtonWithLabel("Add")
+	addButton.ConnectClicked(addRule)
+
+	addBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	addBox.Append(ruleEntry)
+	addBox.Append(addButton)
+
+	closeButton := gtk.NewButtonWithLabel("Close")
+	closeButton.SetHAlign(gtk.AlignEnd)
+	closeButton.ConnectClicked(dialog.Close)
+
+	box.Append(headingLabel)
+	box.Append(helpLabel)
+	box.Append(ignoredScroll)
+	box.Append(addBox)
+	box.Append(errorLabel)
+	box.Append(closeButton)
+
+	dialog.SetVisible(true)
+	ruleEntry.GrabFocus()
+}
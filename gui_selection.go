@@ -0,0 +1,141 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+func (gui *GUI) setupSelectionActions(gtkApp *gtk.Application) {
+	deleteSelectedAction := gio.NewSimpleAction("delete_selected", nil)
+	deleteSelectedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.deleteSelectedItems()
+	})
+	gtkApp.AddAction(deleteSelectedAction)
+
+	pinSelectedAction := gio.NewSimpleAction("pin_selected", glib.NewVariantType("b"))
+	pinSelectedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.pinSelectedItems(parameter.Boolean())
+	})
+	gtkApp.AddAction(pinSelectedAction)
+
+	tagSelectedAction := gio.NewSimpleAction("tag_selected", nil)
+	tagSelectedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showTagSelectedDialog()
+	})
+	gtkApp.AddAction(tagSelectedAction)
+
+	exportSelectedAction := gio.NewSimpleAction("export_selected", nil)
+	exportSelectedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showExportDialog(ItemFilter{ids: gui.selectedItemIDs()})
+	})
+	gtkApp.AddAction(exportSelectedAction)
+
+	mergeSelectedAction := gio.NewSimpleAction("merge_selected", nil)
+	mergeSelectedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.mergeSelectedItems()
+	})
+	gtkApp.AddAction(mergeSelectedAction)
+}
+
+func (gui *GUI) selectedItemIDs() []string {
+	var ids []string
+	for _, row := range gui.clipboardItemsList.SelectedRows() {
+		ids = append(ids, row.Name())
+	}
+	return ids
+}
+
+func (gui *GUI) selectionMenuModel(count int) *gio.Menu {
+	menu := gio.NewMenu()
+	menu.Append("Merge Into One Item", "app.merge_selected")
+
+	editMenu := gio.NewMenu()
+	pinItem := gio.NewMenuItem("Pin", "")
+	pinItem.SetActionAndTargetValue("app.pin_selected", glib.NewVariantBoolean(true))
+	editMenu.AppendItem(pinItem)
+	unpinItem := gio.NewMenuItem("Unpin", "")
+	unpinItem.SetActionAndTargetValue("app.pin_selected", glib.NewVariantBoolean(false))
+	editMenu.AppendItem(unpinItem)
+	editMenu.Append("Add Tags…", "app.tag_selected")
+	editMenu.Append("Export…", "app.export_selected")
+	menu.AppendSection("", editMenu)
+
+	deleteMenu := gio.NewMenu()
+	deleteMenu.Append("Delete "+strconv.Itoa(count)+" Items", "app.delete_selected")
+	menu.AppendSection("", deleteMenu)
+
+	return menu
+}
+
+func (gui *GUI) refreshAfterBulkChange() {
+	glib.IdleAdd(func() {
+		gui.updateClipboardRows(true)
+		gui.focusFirstClipboardListItem()
+	})
+}
+
+func (gui *GUI) deleteSelectedItems() {
+	batch, count, err := clipboard.removeItems(gui.selectedItemIDs())
+	if err != nil {
+		log.Printf("Failed to delete items: %v", err)
+		gui.showToast("Delete failed: " + err.Error())
+		return
+	}
+	gui.showUndoToast(batch, count)
+	gui.refreshAfterBulkChange()
+}
+
+func (gui *GUI) pinSelectedItems(pinned bool) {
+	if err := clipboard.setPinnedItems(gui.selectedItemIDs(), pinned); err != nil {
+		log.Printf("Failed to pin items: %v", err)
+		gui.showToast("Pin failed: " + err.Error())
+		return
+	}
+	gui.refreshAfterBulkChange()
+}
+
+func (gui *GUI) mergeSelectedItems() {
+	if _, err := clipboard.mergeItems(gui.selectedItemIDs(), config.MergeSeparator); err != nil {
+		log.Printf("Failed to merge items: %v", err)
+		gui.showToast("Merge failed: " + err.Error())
+		return
+	}
+	gui.refreshAfterBulkChange()
+}
+
+func (gui *GUI) showTagSelectedDialog() {
+	ids := gui.selectedItemIDs()
+	if len(ids) == 0 {
+		return
+	}
+
+	dialog, box := gui.newDialog("Add Tags")
+
+	tagEntry := gtk.NewEntry()
+	tagEntry.SetPlaceholderText("Tags, separated by commas")
+	tagEntry.SetActivatesDefault(true)
+
+	buttons := gui.newDialogButtons(dialog, "Add", func() {
+		tags := strings.Split(tagEntry.Text(), ",")
+		for _, id := range ids {
+			itemID, _ := strconv.Atoi(id)
+			if err := clipboard.addTags(itemID, tags); err != nil {
+				log.Printf("Failed to tag item %s: %v", id, err)
+				gui.showToast("Tagging failed: " + err.Error())
+				break
+			}
+		}
+		dialog.Close()
+	})
+
+	box.Append(tagEntry)
+	box.Append(buttons)
+
+	dialog.SetVisible(true)
+	tagEntry.GrabFocus()
+}
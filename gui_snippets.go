@@ -0,0 +1,311 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+func (gui *GUI) setupSnippets(gtkApp *gtk.Application) {
+	addSnippetAction := gio.NewSimpleAction("add_snippet", nil)
+	addSnippetAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showSnippetDialog(Snippet{})
+	})
+	gtkApp.AddAction(addSnippetAction)
+
+	gui.mainStack.NotifyProperty("visible-child-name", func() {
+		isSnippetsPage := gui.mainStack.VisibleChildName() == "snippets"
+		gui.addSnippetButton.SetVisible(isSnippetsPage)
+		gui.searchToggleButton.SetVisible(gui.mainStack.VisibleChildName() == "history")
+		if isSnippetsPage {
+			gui.updateSnippetRows()
+		}
+	})
+
+	keyController := gtk.NewEventControllerKey()
+	keyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		selectedRow := gui.snippetList.SelectedRow()
+		if selectedRow == nil {
+			return false
+		}
+		id, _ := strconv.Atoi(selectedRow.Name())
+
+		switch keyval {
+		case gdk.KEY_Return, gdk.KEY_KP_Enter:
+			gui.copySnippet(id)
+			return true
+		case gdk.KEY_Delete:
+			if err := snippets.remove(id); err != nil {
+				log.Printf("Failed to remove snippet: %v", err)
+			}
+			gui.updateSnippetRows()
+			return true
+		case gdk.KEY_F2:
+			gui.editSnippet(id)
+			return true
+		}
+
+		return false
+	})
+
+	gestureClick := gtk.NewGestureClick()
+	gestureClick.ConnectPressed(func(nPress int, x, y float64) {
+		selectedRow := gui.snippetList.SelectedRow()
+		if nPress == 2 && selectedRow != nil {
+			id, _ := strconv.Atoi(selectedRow.Name())
+			gui.copySnippet(id)
+		}
+	})
+
+	gui.snippetList.AddController(keyController)
+	gui.snippetList.AddController(gestureClick)
+}
+
+func (gui *GUI) updateSnippetRows() {
+	gui.snippetList.RemoveAll()
+
+	items, err := snippets.list()
+	if err != nil {
+		log.Printf("Error getting snippets: %v", err)
+		return
+	}
+
+	if len(items) == 0 {
+		placeholder := gtk.NewLabel("No snippets yet. Use the + button to add one.")
+		placeholder.SetMarginTop(24)
+		placeholder.AddCSSClass("dim-label")
+		gui.snippetList.SetPlaceholder(placeholder)
+		return
+	}
+
+	for _, snippet := range items {
+		gui.addSnippetRow(snippet)
+	}
+
+	if firstRow := gui.snippetList.RowAtIndex(0); firstRow != nil {
+		gui.snippetList.SelectRow(firstRow)
+		firstRow.GrabFocus()
+	}
+}
+
+func (gui *GUI) addSnippetRow(snippet Snippet) {
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	box.AddCSSClass("item-box")
+
+	nameLabel := gtk.NewLabel(snippet.name)
+	nameLabel.SetXAlign(0)
+	nameLabel.AddCSSClass("heading")
+
+	content := []rune(snippet.content)
+	if len(content) > 100 {
+		content = append(content[:100], []rune("\n...")...)
+	}
+	contentLabel := gtk.NewLabel(string(content))
+	contentLabel.SetWrap(true)
+	contentLabel.SetWrapMode(pango.WrapWordChar)
+	contentLabel.SetXAlign(0)
+	contentLabel.AddCSSClass("subtitle")
+
+	box.Append(nameLabel)
+	box.Append(contentLabel)
+
+	row := gtk.NewListBoxRow()
+	row.SetName(strconv.Itoa(snippet.id))
+	row.AddCSSClass("item-row")
+	row.SetChild(box)
+
+	gui.snippetList.Append(row)
+}
+
+func (gui *GUI) editSnippet(id int) {
+	snippet, err := snippets.get(strconv.Itoa(id))
+	if err != nil {
+		log.Printf("Failed to get snippet: %v", err)
+		return
+	}
+	gui.showSnippetDialog(snippet)
+}
+
+func (gui *GUI) copySnippet(id int) {
+	snippet, err := snippets.get(strconv.Itoa(id))
+	if err != nil {
+		log.Printf("Failed to get snippet: %v", err)
+		return
+	}
+
+	copyExpanded := func(inputs map[string]string) {
+		clipboard.setText(snippets.expand(snippet.content, clipboard.latestText(), inputs))
+		gui.showToast("Snippet \"" + snippet.name + "\" copied.")
+	}
+
+	inputNames := snippets.inputs(snippet.content)
+	if len(inputNames) == 0 {
+		copyExpanded(nil)
+		return
+	}
+
+	gui.showSnippetInputsDialog(snippet.name, inputNames, copyExpanded)
+}
+
+func (gui *GUI) newDialog(title string) (*gtk.Window, *gtk.Box) {
+	dialog := gtk.NewWindow()
+	dialog.SetTitle(title)
+	if gui.window != nil {
+		dialog.SetTransientFor(&gui.window.Window)
+		dialog.SetModal(true)
+	}
+	dialog.SetDefaultSize(400, -1)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 12)
+	box.SetMarginTop(18)
+	box.SetMarginBottom(18)
+	box.SetMarginStart(18)
+	box.SetMarginEnd(18)
+	dialog.SetChild(box)
+
+	keyController := gtk.NewEventControllerKey()
+	keyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		if keyval == gdk.KEY_Escape {
+			dialog.Close()
+			return true
+		}
+		return false
+	})
+	dialog.AddController(keyController)
+
+	return dialog, box
+}
+
+func (gui *GUI) newDialogButtons(dialog *gtk.Window, acceptLabel string, accept func()) *gtk.Box {
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(func() {
+		dialog.Close()
+	})
+
+	acceptButton := gtk.NewButtonWithLabel(acceptLabel)
+	acceptButton.AddCSSClass("suggested-action")
+	acceptButton.ConnectClicked(accept)
+
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(acceptButton)
+	dialog.SetDefaultWidget(acceptButton)
+
+	return buttonBox
+}
+
+func (gui *GUI) showConfirmDialog(title, message, acceptLabel string, accept func()) {
+	dialog, box := gui.newDialog(title)
+
+	label := gtk.NewLabel(message)
+	label.SetWrap(true)
+	label.SetXAlign(0)
+
+	box.Append(label)
+	box.Append(gui.newDialogButtons(dialog, acceptLabel, func() {
+		dialog.Close()
+		accept()
+	}))
+
+	dialog.SetVisible(true)
+}
+
+func (gui *GUI) showSnippetDialog(snippet Snippet) {
+	title := "New Snippet"
+	if snippet.id != 0 {
+		title = "Edit Snippet"
+	}
+	dialog, box := gui.newDialog(title)
+
+	nameEntry := gtk.NewEntry()
+	nameEntry.SetPlaceholderText("Name")
+	nameEntry.SetText(snippet.name)
+
+	contentView := gtk.NewTextView()
+	contentView.SetWrapMode(gtk.WrapWordChar)
+	contentView.SetMonospace(true)
+	contentView.Buffer().SetText(snippet.content)
+
+	contentScroll := gtk.NewScrolledWindow()
+	contentScroll.SetMinContentHeight(160)
+	contentScroll.SetVExpand(true)
+	contentScroll.SetChild(contentView)
+	contentScroll.AddCSSClass("frame")
+
+	helpLabel := gtk.NewLabel("Placeholders: {{date}}, {{time}}, {{datetime}}, {{clipboard}}, {{uuid}}, {{input:Name}}")
+	helpLabel.SetWrap(true)
+	helpLabel.SetXAlign(0)
+	helpLabel.AddCSSClass("dim-label")
+
+	errorLabel := gtk.NewLabel("")
+	errorLabel.SetVisible(false)
+	errorLabel.SetXAlign(0)
+	errorLabel.AddCSSClass("search-error")
+
+	buttons := gui.newDialogButtons(dialog, "Save", func() {
+		start, end := contentView.Buffer().Bounds()
+		content := contentView.Buffer().Text(start, end, false)
+		if err := snippets.save(snippet.id, nameEntry.Text(), content); err != nil {
+			errorLabel.SetText(err.Error())
+			errorLabel.SetVisible(true)
+			return
+		}
+		dialog.Close()
+		gui.updateSnippetRows()
+	})
+
+	box.Append(nameEntry)
+	box.Append(contentScroll)
+	box.Append(helpLabel)
+	box.Append(errorLabel)
+	box.Append(buttons)
+
+	dialog.SetVisible(true)
+	nameEntry.GrabFocus()
+}
+
+func (gui *GUI) showSnippetInputsDialog(name string, inputNames []string, callback func(inputs map[string]string)) {
+	dialog, box := gui.newDialog(name)
+
+	grid := gtk.NewGrid()
+	grid.SetRowSpacing(6)
+	grid.SetColumnSpacing(12)
+
+	entries := map[string]*gtk.Entry{}
+	for i, inputName := range inputNames {
+		label := gtk.NewLabel(inputName)
+		label.SetXAlign(1)
+		entry := gtk.NewEntry()
+		entry.SetHExpand(true)
+		entry.SetActivatesDefault(true)
+		grid.Attach(label, 0, i, 1, 1)
+		grid.Attach(entry, 1, i, 1, 1)
+		entries[inputName] = entry
+	}
+
+	buttons := gui.newDialogButtons(dialog, "Copy", func() {
+		inputs := map[string]string{}
+		for inputName, entry := range entries {
+			inputs[inputName] = entry.Text()
+		}
+		dialog.Close()
+		callback(inputs)
+	})
+
+	box.Append(grid)
+	box.Append(buttons)
+
+	dialog.SetVisible(true)
+	entries[inputNames[0]].GrabFocus()
+}
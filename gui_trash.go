@@ -0,0 +1,209 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+func (gui *GUI) setupTrash(gtkApp *gtk.Application) {
+	restoreTrashedAction := gio.NewSimpleAction("restore_trashed", nil)
+	restoreTrashedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.restoreSelectedTrashedItems()
+	})
+	gtkApp.AddAction(restoreTrashedAction)
+
+	purgeTrashedAction := gio.NewSimpleAction("purge_trashed", nil)
+	purgeTrashedAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.purgeSelectedTrashedItems()
+	})
+	gtkApp.AddAction(purgeTrashedAction)
+
+	emptyTrashAction := gio.NewSimpleAction("empty_trash", nil)
+	emptyTrashAction.ConnectActivate(func(parameter *glib.Variant) {
+		gui.showConfirmDialog("Empty Trash", "Permanently delete all items in the trash?", "Empty Trash", func() {
+			if _, err := clipboard.emptyTrash(); err != nil {
+				log.Printf("Failed to empty trash: %v", err)
+				gui.showToast("Emptying trash failed: " + err.Error())
+			}
+			gui.updateTrashRows()
+		})
+	})
+	gtkApp.AddAction(emptyTrashAction)
+
+	gui.mainStack.NotifyProperty("visible-child-name", func() {
+		isTrashPage := gui.mainStack.VisibleChildName() == "trash"
+		gui.emptyTrashButton.SetVisible(isTrashPage)
+		if isTrashPage {
+			gui.updateTrashRows()
+		}
+	})
+
+	keyController := gtk.NewEventControllerKey()
+	keyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		if gui.trashList.SelectedRow() == nil {
+			return false
+		}
+
+		switch keyval {
+		case gdk.KEY_Return, gdk.KEY_KP_Enter:
+			gui.restoreSelectedTrashedItems()
+			return true
+		case gdk.KEY_Delete:
+			gui.purgeSelectedTrashedItems()
+			return true
+		}
+
+		return false
+	})
+	gui.trashList.AddController(keyController)
+
+	trashMenu := gio.NewMenu()
+	trashMenu.Append("Restore", "app.restore_trashed")
+	trashMenu.Append("Delete Permanently", "app.purge_trashed")
+	trashContextMenu := gtk.NewPopoverMenuFromModel(trashMenu)
+	trashContextMenu.SetParent(gui.trashList)
+	trashContextMenu.SetHasArrow(false)
+
+	rightClick := gtk.NewGestureClick()
+	rightClick.SetButton(gdk.BUTTON_SECONDARY)
+	rightClick.ConnectPressed(func(nPress int, x, y float64) {
+		row := gui.trashList.RowAtY(int(y))
+		if row == nil {
+			return
+		}
+		if !row.IsSelected() {
+			gui.trashList.UnselectAll()
+			gui.trashList.SelectRow(row)
+		}
+		rect := gdk.NewRectangle(int(x), int(y), 1, 1)
+		trashContextMenu.SetPointingTo(&rect)
+		trashContextMenu.Popup()
+	})
+	gui.trashList.AddController(rightClick)
+}
+
+func (gui *GUI) updateTrashRows() {
+	gui.trashList.RemoveAll()
+
+	items, err := clipboard.trashedItems()
+	if err != nil {
+		log.Printf("Error getting trashed items: %v", err)
+		return
+	}
+
+	if len(items) == 0 {
+		placeholder := gtk.NewLabel("The trash is empty.")
+		placeholder.SetMarginTop(24)
+		placeholder.AddCSSClass("dim-label")
+		gui.trashList.SetPlaceholder(placeholder)
+		return
+	}
+
+	for _, item := range items {
+		gui.addTrashRow(item)
+	}
+}
+
+func (gui *GUI) addTrashRow(item ClipboardItem) {
+	rowBox := gtk.NewBox(gtk.OrientationHorizontal, 12)
+	rowBox.SetMarginStart(12)
+	rowBox.SetMarginEnd(12)
+
+	kindIcon := gtk.NewImageFromIconName(kindIcons[item.kind])
+	kindIcon.SetVAlign(gtk.AlignStart)
+	kindIcon.SetMarginTop(14)
+	kindIcon.AddCSSClass("item-kind")
+	rowBox.Append(kindIcon)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetHExpand(true)
+	box.AddCSSClass("item-box")
+
+	content := item.content
+	if item.itemType == 2 {
+		content = "Image"
+	} else if len(content) > 100 {
+		content = content[:100] + "\n..."
+	}
+	contentLabel := gtk.NewLabel(content)
+	contentLabel.SetWrap(true)
+	contentLabel.SetWrapMode(pango.WrapWordChar)
+	contentLabel.SetXAlign(0)
+	contentLabel.AddCSSClass("title")
+
+	deletedAt, _, _ := strings.Cut(item.deletedAt, ".")
+	dateLabel := gtk.NewLabel(item.dateTime + " · Deleted " + deletedAt)
+	dateLabel.SetXAlign(0)
+	dateLabel.AddCSSClass("subtitle")
+
+	box.Append(contentLabel)
+	box.Append(dateLabel)
+	rowBox.Append(box)
+
+	row := gtk.NewListBoxRow()
+	row.SetName(strconv.Itoa(item.id))
+	row.AddCSSClass("item-row")
+	row.SetChild(rowBox)
+
+	gui.trashList.Append(row)
+}
+
+func (gui *GUI) selectedTrashedItemIDs() []string {
+	var ids []string
+	for _, row := range gui.trashList.SelectedRows() {
+		ids = append(ids, row.Name())
+	}
+	return ids
+}
+
+func (gui *GUI) restoreSelectedTrashedItems() {
+	if err := clipboard.restoreItems(gui.selectedTrashedItemIDs()); err != nil {
+		log.Printf("Failed to restore items: %v", err)
+		gui.showToast("Restore failed: " + err.Error())
+		return
+	}
+	gui.updateTrashRows()
+}
+
+func (gui *GUI) purgeSelectedTrashedItems() {
+	ids := gui.selectedTrashedItemIDs()
+	if len(ids) == 0 {
+		return
+	}
+	message := "Permanently delete " + strconv.Itoa(len(ids)) + " items?"
+	if len(ids) == 1 {
+		message = "Permanently delete this item?"
+	}
+	gui.showConfirmDialog("Delete Permanently", message, "Delete", func() {
+		if _, err := clipboard.purgeItems(ids); err != nil {
+			log.Printf("Failed to delete items: %v", err)
+			gui.showToast("Delete failed: " + err.Error())
+		}
+		gui.updateTrashRows()
+	})
+}
+
+func (gui *GUI) showUndoToast(batch string, count int64) {
+	if count == 0 {
+		return
+	}
+	message := "Moved " + strconv.FormatInt(count, 10) + " items to the trash."
+	if count == 1 {
+		message = "Moved item to the trash."
+	}
+	gui.showActionToast(message, "Undo", func() {
+		if _, err := clipboard.restore(batch); err != nil {
+			log.Printf("Failed to restore items: %v", err)
+			gui.showToast("Undo failed: " + err.Error())
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const (
+	hookExitAccept = 0
+	hookExitVeto   = 1
+)
+
+type Hooks struct {
+	queue chan hookJob
+	once  sync.Once
+}
+
+type HookEntry struct {
+	content  string
+	itemType byte
+	kind     string
+	language string
+	source   SourceApp
+}
+
+type hookJob struct {
+	entry HookEntry
+	save  func(entry HookEntry)
+}
+
+func (hooks *Hooks) dir() string {
+	return config.dir() + "/hooks"
+}
+
+func (hooks *Hooks) scripts() []string {
+	dirEntries, err := os.ReadDir(hooks.dir())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read hooks directory: %v", err)
+		}
+		return nil
+	}
+
+	var scripts []string
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(hooks.dir(), name))
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		scripts = append(scripts, filepath.Join(hooks.dir(), name))
+	}
+	sort.Strings(scripts)
+
+	return scripts
+}
+
+func (hooks *Hooks) process(entry HookEntry, save func(entry HookEntry)) {
+	if len(hooks.scripts()) == 0 {
+		save(entry)
+		return
+	}
+
+	hooks.once.Do(func() {
+		hooks.queue = make(chan hookJob, 64)
+		go hooks.worker()
+	})
+	select {
+	case hooks.queue <- hookJob{entry: entry, save: save}:
+	default:
+		log.Printf("Hook queue is full, dropped a %s entry", itemTypeNames[entry.itemType])
+		notifier.error("Hook queue is full, dropped a %s entry", itemTypeNames[entry.itemType])
+	}
+}
+
+func (hooks *Hooks) worker() {
+	for job := range hooks.queue {
+		entry, accepted := hooks.run(job.entry)
+		if !accepted {
+			continue
+		}
+		save := job.save
+		glib.IdleAdd(func() {
+			save(entry)
+		})
+	}
+}
+
+func (hooks *Hooks) run(entry HookEntry) (HookEntry, bool) {
+	for _, script := range hooks.scripts() {
+		name := filepath.Base(script)
+		output, exitCode, err := hooks.runScript(script, entry)
+		if err != nil {
+			log.Printf("Hook %s failed: %v", name, err)
+			glib.IdleAdd(func() {
+				notifier.error("Hook %s failed: %v", name, err)
+			})
+			continue
+		}
+
+		switch exitCode {
+		case hookExitAccept:
+			if entry.itemType == 1 && len(output) > 0 {
+				content := strings.TrimSpace(string(output))
+				if content != "" && content != entry.content {
+					entry.content = content
+					entry.kind, entry.language = classifyContent(content, entry.itemType)
+				}
+			}
+		case hookExitVeto:
+			glib.IdleAdd(func() {
+				notifier.filtered("Rejected by hook " + name)
+			})
+			return entry, false
+		default:
+			log.Printf("Hook %s exited with status %d", name, exitCode)
+		}
+	}
+
+	return entry, true
+}
+
+func (hooks *Hooks) runScript(script string, entry HookEntry) ([]byte, int, error) {
+	input := []byte(entry.content)
+	if entry.itemType == 2 {
+		imageData, err := base64.StdEncoding.DecodeString(entry.content)
+		if err != nil {
+			return nil, 0, err
+		}
+		input = imageData
+	}
+
+	timeout := time.Duration(max(config.HookTimeoutSeconds, 1)) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"CLYP_TYPE="+itemTypeName(entry.itemType),
+		"CLYP_KIND="+entry.kind,
+		"CLYP_LANGUAGE="+entry.language,
+		"CLYP_SOURCE_APP="+entry.source.app,
+		"CLYP_SOURCE_TITLE="+entry.source.title,
+		"CLYP_DATE_TIME="+time.Now().Format(time.RFC3339),
+		fmt.Sprintf("CLYP_SIZE=%d", len(input)),
+	)
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, 0, fmt.Errorf("timed out after %s", timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.Bytes(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return stdout.Bytes(), hookExitAccept, nil
+}
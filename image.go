@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+)
+
+const (
+	imageOversizeDownscale = "downscale"
+	imageOversizeSkip      = "skip"
+
+	imageFormatOriginal = "original"
+	imageFormatPNG      = "png"
+	imageFormatJPEG     = "jpeg"
+
+	imageMaxReadBytes = 256 << 20
+	// imageMaxPixels is the largest image that is decoded to be downscaled,
+	// up to 512 MiB with the decoded image and its RGBA copy.
+	imageMaxPixels = 64 << 20
+)
+
+var (
+	errDuplicateFrame = errors.New("duplicate frame")
+	errImageTooLarge  = errors.New("image is too large")
+)
+
+type CapturedImage struct {
+	data  []byte
+	frame string
+}
+
+// imageMIMEType returns the type of encoded image data for the formats that
+// are stored as captured, other formats are converted to PNG.
+func imageMIMEType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	}
+	return ""
+}
+
+// encodedImageMIMEType returns the type of base64 encoded image content.
+func encodedImageMIMEType(content string) string {
+	header, _ := base64.StdEncoding.DecodeString(content[:min(len(content), 12)])
+	if mimeType := imageMIMEType(header); mimeType != "" {
+		return mimeType
+	}
+	return "image/png"
+}
+
+func imageExtension(data []byte) string {
+	if imageMIMEType(data) == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// processImage applies the configured limits and format to captured image
+// data, recentFrame is the frame hash of the previous image.
+func processImage(data []byte, recentFrame string) (CapturedImage, error) {
+	// The dimensions are checked in the header first, a small file can hold an
+	// image too large to decode.
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return CapturedImage{}, err
+	}
+	width, height := header.Width, header.Height
+	maxDimension := config.ImageMaxDimension
+	oversized := maxDimension > 0 && max(width, height) > maxDimension
+	if oversized && config.ImageOversize == imageOversizeSkip {
+		return CapturedImage{}, fmt.Errorf("%w: %d×%d pixels, the limit is %d", errImageTooLarge, width, height, maxDimension)
+	}
+	if width*height > imageMaxPixels {
+		return CapturedImage{}, fmt.Errorf("%w: %d×%d pixels can not be processed", errImageTooLarge, width, height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return CapturedImage{}, err
+	}
+	pixels := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(pixels, pixels.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+
+	captured := CapturedImage{data: data, frame: frameHash(pixels)}
+	if captured.frame == recentFrame {
+		return captured, errDuplicateFrame
+	}
+
+	format := imageFormatFor(data)
+	if oversized {
+		pixels = scaleImage(pixels, float64(maxDimension)/float64(max(width, height)))
+	}
+
+	// Images that need no changes keep their original bytes, so copying them
+	// back and capturing them again gives the same content.
+	if oversized || imageMIMEType(data) != "image/"+format {
+		if captured.data, err = encodeImage(pixels, format); err != nil {
+			return captured, err
+		}
+	}
+
+	maxBytes := config.ImageMaxSizeKB << 10
+	if maxBytes <= 0 || len(captured.data) <= maxBytes {
+		return captured, nil
+	}
+	if config.ImageOversize == imageOversizeSkip {
+		return captured, fmt.Errorf("%w: %d KB, the limit is %d KB", errImageTooLarge, len(captured.data)>>10, config.ImageMaxSizeKB)
+	}
+	for attempt := 0; attempt < 5 && len(captured.data) > maxBytes; attempt++ {
+		pixels = scaleImage(pixels, math.Sqrt(float64(maxBytes)/float64(len(captured.data)))*0.9)
+		if captured.data, err = encodeImage(pixels, format); err != nil {
+			return captured, err
+		}
+	}
+	if len(captured.data) > maxBytes {
+		return captured, fmt.Errorf("%w: could not reduce it below %d KB", errImageTooLarge, config.ImageMaxSizeKB)
+	}
+
+	return captured, nil
+}
+
+func imageFormatFor(data []byte) string {
+	switch config.ImageFormat {
+	case imageFormatPNG, imageFormatJPEG:
+		return config.ImageFormat
+	}
+	if imageMIMEType(data) == "image/jpeg" {
+		return imageFormatJPEG
+	}
+	return imageFormatPNG
+}
+
+func encodeImage(pixels *image.RGBA, format string) ([]byte, error) {
+	var buffer bytes.Buffer
+	if format == imageFormatJPEG {
+		// JPEG has no transparency, transparent areas become white.
+		flattened := image.NewRGBA(pixels.Bounds())
+		draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flattened, flattened.Bounds(), pixels, image.Point{}, draw.Over)
+		err := jpeg.Encode(&buffer, flattened, &jpeg.Options{Quality: min(max(config.ImageQuality, 1), 100)})
+		return buffer.Bytes(), err
+	}
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	err := encoder.Encode(&buffer, pixels)
+	return buffer.Bytes(), err
+}
+
+// scaleImage shrinks an image by averaging the source pixels covered by each
+// target pixel.
+func scaleImage(source *image.RGBA, scale float64) *image.RGBA {
+	sourceWidth, sourceHeight := source.Bounds().Dx(), source.Bounds().Dy()
+	width := max(int(float64(sourceWidth)*scale), 1)
+	height := max(int(float64(sourceHeight)*scale), 1)
+	target := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sourceHeight/height, max((y+1)*sourceHeight/height, y*sourceHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sourceWidth/width, max((x+1)*sourceWidth/width, x*sourceWidth/width+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := source.Pix[sy*source.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for channel := range sum {
+						sum[channel] += int(row[sx*4+channel])
+					}
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*target.Stride + x*4
+			for channel := range sum {
+				target.Pix[offset+channel] = uint8(sum[channel] / count)
+			}
+		}
+	}
+
+	return target
+}
+
+func frameHash(pixels *image.RGBA) string {
+	hash := sha256.New()
+	binary.Write(hash, binary.LittleEndian, [2]int32{int32(pixels.Bounds().Dx()), int32(pixels.Bounds().Dy())})
+	hash.Write(pixels.Pix)
+	return hex.EncodeToString(hash.Sum(nil))
+}
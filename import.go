@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+type ImportEntry struct {
+	content  string
+	itemType byte
+	dateTime time.Time
+	tags     []string
+}
+
+type ImportResult struct {
+	imported int
+	skipped  int
+	failed   int
+}
+
+type importSource struct {
+	defaultPath func() string
+	read        func(path string) ([]ImportEntry, error)
+}
+
+var importSources = map[string]importSource{
+	"clyp": {
+		read: readClypImport,
+	},
+	"copyq": {
+		read: readCopyQImport,
+	},
+	"gpaste": {
+		defaultPath: func() string { return glib.GetUserDataDir() + "/gpaste/history.xml" },
+		read:        readGPasteImport,
+	},
+	"cliphist": {
+		read: readCliphistImport,
+	},
+	"clipman": {
+		defaultPath: func() string { return glib.GetUserDataDir() + "/clipman.json" },
+		read:        readClipmanImport,
+	},
+	"klipper": {
+		defaultPath: func() string { return glib.GetUserDataDir() + "/klipper/history2.lst" },
+		read:        readKlipperImport,
+	},
+}
+
+func importSourceNames() []string {
+	return []string{"clyp", "copyq", "gpaste", "cliphist", "clipman", "klipper"}
+}
+
+func (clipboard *Clipboard) importEntries(entries []ImportEntry) (ImportResult, error) {
+	var result ImportResult
+
+	tx, err := database.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		if entry.itemType == 1 {
+			entry.content = strings.TrimSpace(entry.content)
+		}
+		if entry.content == "" {
+			result.skipped++
+			continue
+		}
+
+		hash := contentHash(entry.content, entry.itemType)
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM clipboard WHERE hash=?", hash).Scan(&exists); err != nil {
+			return ImportResult{}, err
+		}
+		if exists > 0 {
+			result.skipped++
+			continue
+		}
+
+		dateTime := entry.dateTime
+		if dateTime.IsZero() {
+			dateTime = time.Now()
+		}
+		kind, language := classifyContent(entry.content, entry.itemType)
+		res, err := tx.Exec("INSERT INTO clipboard (content, type, date_time, kind, language, hash, imported) VALUES (?, ?, ?, ?, ?, ?, 1)", entry.content, entry.itemType, dateTime.UTC().Format(time.DateTime), kind, language, hash)
+		if err != nil {
+			result.failed++
+			continue
+		}
+		if id, err := res.LastInsertId(); err == nil {
+			for _, tag := range entry.tags {
+				tx.Exec("INSERT OR IGNORE INTO clipboard_tags (item_id, tag) VALUES (?, ?)", id, tag)
+			}
+		}
+		result.imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, err
+	}
+
+	return result, nil
+}
+
+func orderedImportTimes(entries []ImportEntry) []ImportEntry {
+	now := time.Now()
+	for i := range entries {
+		if entries[i].dateTime.IsZero() {
+			entries[i].dateTime = now.Add(-time.Duration(i) * time.Second)
+		}
+	}
+	return entries
+}
+
+func importImage(data []byte) (string, error) {
+	if imageMIMEType(data) != "" {
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, decoded); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+func readClypImport(path string) ([]ImportEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []ExportItem
+	var document ExportDocument
+	if err := json.Unmarshal(data, &document); err == nil {
+		if document.Version > exportSchemaVersion {
+			return nil, fmt.Errorf("unsupported export version %d", document.Version)
+		}
+		items = document.Items
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var item ExportItem
+			if err := decoder.Decode(&item); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+
+	var entries []ImportEntry
+	for _, item := range items {
+		entry := ImportEntry{tags: item.Tags}
+		if dateTime, err := time.Parse(time.RFC3339, item.DateTime); err == nil {
+			entry.dateTime = dateTime
+		}
+		switch item.Type {
+		case "text":
+			entry.itemType = 1
+			entry.content = item.Content
+		case "image":
+			entry.itemType = 2
+			entry.content = item.Image
+			if item.ImageFile != "" {
+				imageData, err := os.ReadFile(filepath.Join(filepath.Dir(path), item.ImageFile))
+				if err != nil {
+					return nil, err
+				}
+				entry.content = base64.StdEncoding.EncodeToString(imageData)
+			}
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+const copyQExportScript = `
+var items = [];
+for (var i = 0; i < size(); ++i) {
+	var item = getItem(i);
+	if (item['text/plain'] !== undefined) {
+		items.push({text: str(item['text/plain'])});
+	} else if (item['image/png'] !== undefined) {
+		items.push({image: str(toBase64(item['image/png']))});
+	}
+}
+print(JSON.stringify(items));
+`
+
+func readCopyQImport(tab string) ([]ImportEntry, error) {
+	args := []string{"eval", "--", copyQExportScript}
+	if tab != "" {
+		args = append([]string{"tab", tab}, args...)
+	}
+	output, err := exec.Command("copyq", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("copyq: %v", err)
+	}
+
+	var items []struct {
+		Text  string `json:"text"`
+		Image string `json:"image"`
+	}
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, err
+	}
+
+	var entries []ImportEntry
+	for _, item := range items {
+		if item.Image != "" {
+			entries = append(entries, ImportEntry{content: item.Image, itemType: 2})
+		} else {
+			entries = append(entries, ImportEntry{content: item.Text, itemType: 1})
+		}
+	}
+
+	return orderedImportTimes(entries), nil
+}
+
+func readGPasteImport(path string) ([]ImportEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var history struct {
+		Items []struct {
+			Kind  string `xml:"kind,attr"`
+			Date  string `xml:"date,attr"`
+			Value string `xml:"value"`
+		} `xml:"item"`
+	}
+	if err := xml.NewDecoder(file).Decode(&history); err != nil {
+		return nil, err
+	}
+
+	var entries []ImportEntry
+	for _, item := range history.Items {
+		var entry ImportEntry
+		switch item.Kind {
+		case "Text", "Uris":
+			entry = ImportEntry{content: item.Value, itemType: 1}
+		case "Image":
+			imageData, err := os.ReadFile(item.Value)
+			if err != nil {
+				continue
+			}
+			content, err := importImage(imageData)
+			if err != nil {
+				continue
+			}
+			entry = ImportEntry{content: content, itemType: 2}
+		default:
+			continue
+		}
+		if seconds, err := strconv.ParseInt(item.Date, 10, 64); err == nil {
+			entry.dateTime = time.Unix(seconds, 0)
+		}
+		entries = append(entries, entry)
+	}
+
+	return orderedImportTimes(entries), nil
+}
+
+func readCliphistImport(_ string) ([]ImportEntry, error) {
+	output, err := exec.Command("cliphist", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cliphist: %v", err)
+	}
+
+	var entries []ImportEntry
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		decode := exec.Command("cliphist", "decode")
+		decode.Stdin = strings.NewReader(line + "\n")
+		data, err := decode.Output()
+		if err != nil {
+			continue
+		}
+		if strings.Contains(line, "[[ binary data") {
+			content, err := importImage(data)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, ImportEntry{content: content, itemType: 2})
+		} else {
+			entries = append(entries, ImportEntry{content: string(data), itemType: 1})
+		}
+	}
+
+	return orderedImportTimes(entries), scanner.Err()
+}
+
+func readClipmanImport(path string) ([]ImportEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var texts []string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &texts); err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(texts)-1; i < j; i, j = i+1, j-1 {
+			texts[i], texts[j] = texts[j], texts[i]
+		}
+	} else {
+		keyFile := glib.NewKeyFile()
+		if err := keyFile.LoadFromData(string(data), glib.KeyFileNone); err != nil {
+			return nil, fmt.Errorf("unsupported clipman history file: %v", err)
+		}
+		texts, err = keyFile.StringList("texts", "texts")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []ImportEntry
+	for _, text := range texts {
+		entries = append(entries, ImportEntry{content: text, itemType: 1})
+	}
+
+	return orderedImportTimes(entries), nil
+}
+
+type qDataStream struct {
+	reader *bytes.Reader
+}
+
+func (stream *qDataStream) uint32() (uint32, error) {
+	var value uint32
+	err := binary.Read(stream.reader, binary.BigEndian, &value)
+	return value, err
+}
+
+func (stream *qDataStream) byteArray() ([]byte, error) {
+	length, err := stream.uint32()
+	if err != nil || length == 0xFFFFFFFF {
+		return nil, err
+	}
+	if int64(length) > int64(stream.reader.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(stream.reader, data)
+	return data, err
+}
+
+func (stream *qDataStream) string() (string, error) {
+	data, err := stream.byteArray()
+	if err != nil {
+		return "", err
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func (stream *qDataStream) png() ([]byte, error) {
+	start := int(stream.reader.Size()) - stream.reader.Len()
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(stream.reader, header); err != nil {
+		return nil, err
+	}
+	for {
+		length, err := stream.uint32()
+		if err != nil {
+			return nil, err
+		}
+		chunkType := make([]byte, 4)
+		if _, err := io.ReadFull(stream.reader, chunkType); err != nil {
+			return nil, err
+		}
+		if _, err := stream.reader.Seek(int64(length)+4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		if string(chunkType) == "IEND" {
+			break
+		}
+	}
+	end := int(stream.reader.Size()) - stream.reader.Len()
+	data := make([]byte, end-start)
+	_, err := stream.reader.ReadAt(data, int64(start))
+	return data, err
+}
+
+func readKlipperImport(path string) ([]ImportEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileStream := qDataStream{bytes.NewReader(data)}
+	if _, err := fileStream.uint32(); err != nil {
+		return nil, err
+	}
+	history, err := fileStream.byteArray()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := qDataStream{bytes.NewReader(history)}
+	if _, err := stream.byteArray(); err != nil {
+		return nil, err
+	}
+
+	var entries []ImportEntry
+	for stream.reader.Len() > 0 {
+		itemType, err := stream.string()
+		if err != nil {
+			return nil, err
+		}
+		switch itemType {
+		case "string":
+			text, err := stream.string()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, ImportEntry{content: text, itemType: 1})
+		case "url":
+			urlCount, err := stream.uint32()
+			if err != nil {
+				return nil, err
+			}
+			var urls []string
+			for range urlCount {
+				url, err := stream.byteArray()
+				if err != nil {
+					return nil, err
+				}
+				urls = append(urls, string(url))
+			}
+			metaDataCount, err := stream.uint32()
+			if err != nil {
+				return nil, err
+			}
+			for range metaDataCount * 2 {
+				if _, err := stream.string(); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := stream.uint32(); err != nil {
+				return nil, err
+			}
+			entries = append(entries, ImportEntry{content: strings.Join(urls, "\n"), itemType: 1})
+		case "image":
+			if marker, err := stream.uint32(); err != nil || marker == 0 {
+				return nil, errors.New("invalid image item")
+			}
+			imageData, err := stream.png()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, ImportEntry{content: base64.StdEncoding.EncodeToString(imageData), itemType: 2})
+		default:
+			return nil, fmt.Errorf("unsupported klipper item type %q", itemType)
+		}
+	}
+
+	return orderedImportTimes(entries), nil
+}
@@ -1,18 +1,38 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"syscall"
 
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 )
 
+const (
+	guiSocket         = "/tmp/clyp.sock"
+	watcherSocketName = "clyp-watcher.sock"
+)
+
 type IPC struct{}
 
+type IPCRequest struct {
+	Command    string   `json:"command"`
+	ID         string   `json:"id,omitempty"`
+	Text       string   `json:"text,omitempty"`
+	Hash       string   `json:"hash,omitempty"`
+	Transforms []string `json:"transforms,omitempty"`
+}
+
+type IPCResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
 func (ipc *IPC) notify() {
-	conn, err := net.Dial("unix", "/tmp/clyp.sock")
+	conn, err := net.Dial("unix", guiSocket)
 	if err != nil {
 		return
 	}
@@ -25,8 +45,8 @@ func (ipc *IPC) notify() {
 }
 
 func (ipc *IPC) listen() {
-	os.Remove("/tmp/clyp.sock")
-	listener, err := net.Listen("unix", "/tmp/clyp.sock")
+	os.Remove(guiSocket)
+	listener, err := net.Listen("unix", guiSocket)
 	if err != nil {
 		fmt.Printf("%v", err)
 		return
@@ -46,7 +66,110 @@ func (ipc *IPC) listen() {
 		glib.IdleAdd(func() {
 			gui.updateClipboardRows(true)
 			gui.focusFirstClipboardListItem()
+			if gui.mainStack.VisibleChildName() == "trash" {
+				gui.updateTrashRows()
+			}
 		})
 		conn.Close()
 	}
 }
+
+func (ipc *IPC) request(request IPCRequest) error {
+	conn, err := net.Dial("unix", watcherSocketPath())
+	if err != nil {
+		return fmt.Errorf("watcher is not running, start it with \"clyp watch\"")
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return err
+	}
+
+	var response IPCResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s", response.Error)
+	}
+
+	return nil
+}
+
+func watcherSocketPath() string {
+	return glib.GetUserRuntimeDir() + "/" + watcherSocketName
+}
+
+// listenPrivateSocket creates a socket under a umask that keeps other users
+// out from the start, the chmod only makes the mode explicit.
+func listenPrivateSocket(path string) (net.Listener, error) {
+	os.Remove(path)
+
+	umask := syscall.Umask(0077)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(umask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func (ipc *IPC) listenWatcher() {
+	listener, err := listenPrivateSocket(watcherSocketPath())
+	if err != nil {
+		log.Printf("Failed to listen on watcher socket: %v", err)
+		return
+	}
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			continue
+		}
+		go ipc.handleWatcherConn(conn)
+	}
+}
+
+func (ipc *IPC) handleWatcherConn(conn net.Conn) {
+	defer conn.Close()
+
+	var request IPCRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&request); err != nil {
+		log.Printf("Failed to read from watcher socket: %v", err)
+		return
+	}
+
+	result := make(chan error, 1)
+	glib.IdleAdd(func() {
+		result <- ipc.handleWatcherRequest(request)
+	})
+
+	var response IPCResponse
+	if err := <-result; err != nil {
+		response.Error = err.Error()
+	}
+	json.NewEncoder(conn).Encode(response)
+}
+
+func (ipc *IPC) handleWatcherRequest(request IPCRequest) error {
+	switch request.Command {
+	case "copy":
+		return clipboard.copy(request.ID, request.Transforms...)
+	case "copy_text":
+		clipboard.setText(request.Text)
+		return nil
+	case "skip_capture":
+		clipboard.recentHash = request.Hash
+		return nil
+	case "reload_config":
+		config.load()
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", request.Command)
+	}
+}
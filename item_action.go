@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+type ItemAction struct {
+	id    string
+	label string
+	icon  string
+	kinds []string
+	quick bool
+	run   func(item ClipboardItem) error
+}
+
+type ActionRegistry struct {
+	actions []ItemAction
+}
+
+type CustomAction struct {
+	Label   string   `json:"label"`
+	Icon    string   `json:"icon,omitempty"`
+	Kinds   []string `json:"kinds,omitempty"`
+	Command []string `json:"command"`
+}
+
+func (registry *ActionRegistry) register(action ItemAction) {
+	registry.actions = slices.DeleteFunc(registry.actions, func(registered ItemAction) bool {
+		return registered.id == action.id
+	})
+	registry.actions = append(registry.actions, action)
+}
+
+func (registry *ActionRegistry) find(id string) (ItemAction, bool) {
+	for _, action := range registry.actions {
+		if action.id == id {
+			return action, true
+		}
+	}
+	return ItemAction{}, false
+}
+
+func (registry *ActionRegistry) forItem(item ClipboardItem) []ItemAction {
+	var actions []ItemAction
+	for _, action := range registry.actions {
+		if action.appliesTo(item) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func (action ItemAction) appliesTo(item ClipboardItem) bool {
+	if len(action.kinds) == 0 {
+		return item.itemType == 1
+	}
+	return slices.Contains(action.kinds, item.kind)
+}
+
+func (registry *ActionRegistry) registerCustomActions(customActions []CustomAction) {
+	for i, customAction := range customActions {
+		if customAction.Label == "" || len(customAction.Command) == 0 {
+			continue
+		}
+		command := customAction.Command
+		icon := customAction.Icon
+		if icon == "" {
+			icon = "system-run-symbolic"
+		}
+		registry.register(ItemAction{
+			id:    fmt.Sprintf("custom-%d", i),
+			label: customAction.Label,
+			icon:  icon,
+			kinds: customAction.Kinds,
+			run: func(item ClipboardItem) error {
+				return runCustomAction(command, item)
+			},
+		})
+	}
+}
+
+func runCustomAction(command []string, item ClipboardItem) error {
+	content := strings.TrimSpace(item.content)
+	replacer := strings.NewReplacer("{}", content, "{url}", url.QueryEscape(content))
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(item.content)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+
+	return nil
+}
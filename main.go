@@ -1,15 +1,26 @@
 package main
 
 import (
+	"fmt"
 	"os"
 )
 
 var (
-	app      Application
-	gui      GUI
-	service  Service
-	database Database
-	ipc      IPC
+	app       Application
+	gui       GUI
+	service   Service
+	database  Database
+	ipc       IPC
+	cli       CLI
+	config    Config
+	snippets  Snippets
+	hooks     Hooks
+	dbus      DBus
+	api       API
+	popup     Popup
+	shortcuts Shortcuts
+	tray      Tray
+	notifier  Notifier
 )
 
 func main() {
@@ -17,15 +28,23 @@ func main() {
 	app.name = "Clyp"
 
 	app.setupDataDir()
-	database.init()
+	config.load()
+	if err := database.init(); err != nil {
+		fmt.Fprintf(os.Stderr, "clyp: %v\n", err)
+		os.Exit(1)
+	}
 
-	switch len(os.Args) {
-	case 1:
+	if len(os.Args) == 1 {
 		gui.init()
-	case 2:
-		switch os.Args[1] {
-		case "watch":
-			service.init()
-		}
+		return
+	}
+
+	switch os.Args[1] {
+	case "watch":
+		service.init()
+	case "popup":
+		popup.init()
+	default:
+		os.Exit(cli.run(os.Args[1:]))
 	}
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+const notificationPreviewLength = 120
+
+type Notifier struct {
+	application *gtk.Application
+	sent        map[string]time.Time
+	suppressed  map[string]int
+}
+
+// init sets up notifications for the watcher or the GUI, copies made in the
+// main window are notified by the GUI process. open is run when a
+// notification is clicked.
+func (notifier *Notifier) init(application *gtk.Application, open func()) {
+	notifier.application = application
+	notifier.sent = make(map[string]time.Time)
+	notifier.suppressed = make(map[string]int)
+
+	openAction := gio.NewSimpleAction("open", nil)
+	openAction.ConnectActivate(func(parameter *glib.Variant) {
+		open()
+	})
+	application.AddAction(openAction)
+}
+
+func (notifier *Notifier) captured(item ClipboardItem) {
+	if !config.NotifyCaptured {
+		return
+	}
+	notifier.send("captured", "Clipboard Item Captured", notificationPreview(item), notificationIcon(item))
+}
+
+func (notifier *Notifier) copied(content string, itemType byte) {
+	if !config.NotifyCopied {
+		return
+	}
+	item := ClipboardItem{content: content, itemType: itemType}
+	notifier.send("copied", "Copied to Clipboard", notificationPreview(item), notificationIcon(item))
+}
+
+func (notifier *Notifier) filtered(reason string) {
+	if !config.NotifyFiltered {
+		return
+	}
+	notifier.send("filtered", "Clipboard Entry Not Saved", reason, gio.NewThemedIcon("security-high-symbolic"))
+}
+
+func (notifier *Notifier) error(format string, args ...any) {
+	if !config.NotifyErrors {
+		return
+	}
+	notifier.send("error", "Clipboard Watcher Error", fmt.Sprintf(format, args...), gio.NewThemedIcon("dialog-error-symbolic"))
+}
+
+func (notifier *Notifier) send(category, title, body string, icon gio.Iconner) {
+	if notifier.application == nil {
+		return
+	}
+
+	interval := time.Duration(config.NotifyIntervalSeconds) * time.Second
+	if time.Since(notifier.sent[category]) < interval {
+		notifier.suppressed[category]++
+		return
+	}
+	if suppressed := notifier.suppressed[category]; suppressed > 0 {
+		title = fmt.Sprintf("%s (+%d more)", title, suppressed)
+	}
+	notifier.sent[category] = time.Now()
+	notifier.suppressed[category] = 0
+
+	notification := gio.NewNotification(title)
+	notification.SetBody(body)
+	notification.SetIcon(icon)
+	notification.SetDefaultAction("app.open")
+	if category == "error" {
+		notification.SetPriority(gio.NotificationPriorityHigh)
+	}
+	notifier.application.SendNotification("clyp-"+category, notification)
+}
+
+func notificationPreview(item ClipboardItem) string {
+	if item.itemType == 2 {
+		return "Image"
+	}
+	preview := []rune(strings.Join(strings.Fields(item.content), " "))
+	if len(preview) > notificationPreviewLength {
+		preview = append(preview[:notificationPreviewLength], '…')
+	}
+	return string(preview)
+}
+
+func notificationIcon(item ClipboardItem) gio.Iconner {
+	if item.itemType == 2 {
+		if imageData, err := base64.StdEncoding.DecodeString(item.content); err == nil {
+			return gio.NewBytesIcon(glib.NewBytesWithGo(imageData))
+		}
+	}
+	if icon, ok := kindIcons[item.kind]; ok {
+		return gio.NewThemedIcon(icon)
+	}
+	return gio.NewThemedIcon("bio.murat.clyp")
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const (
+	remoteDesktopInterface = "org.freedesktop.portal.RemoteDesktop"
+	remoteDesktopKeyboard  = 1
+	remoteDesktopPersist   = 2
+	keysymControlL         = 0xffe3
+	keysymV                = 0x76
+)
+
+type PasteBackend interface {
+	paste(done func(err error))
+}
+
+var pasteBackends = map[string]func() PasteBackend{
+	"wtype":   func() PasteBackend { return commandPasteBackend{"wtype", "-M", "ctrl", "v", "-m", "ctrl"} },
+	"ydotool": func() PasteBackend { return commandPasteBackend{"ydotool", "key", "29:1", "47:1", "47:0", "29:0"} },
+	"xdotool": func() PasteBackend { return commandPasteBackend{"xdotool", "key", "--clearmodifiers", "ctrl+v"} },
+	"portal":  func() PasteBackend { return &portalPasteBackend{} },
+	"fake":    func() PasteBackend { return &fakePasteBackend{} },
+}
+
+var pasteBackend PasteBackend
+
+func autoPaste(hide func(), done func()) {
+	if !config.AutoPaste {
+		done()
+		return
+	}
+
+	if pasteBackend == nil {
+		backend, err := newPasteBackend(config.PasteBackend)
+		if err != nil {
+			log.Printf("Auto-paste disabled: %v", err)
+			done()
+			return
+		}
+		pasteBackend = backend
+	}
+
+	hide()
+	glib.TimeoutAdd(uint(max(config.PasteDelayMS, 0)), func() bool {
+		pasteBackend.paste(func(err error) {
+			if err != nil {
+				log.Printf("Failed to paste: %v", err)
+			}
+			done()
+		})
+		return false
+	})
+}
+
+func newPasteBackend(name string) (PasteBackend, error) {
+	if name == "" || name == "auto" {
+		name = detectPasteBackend()
+	}
+	newBackend, ok := pasteBackends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown paste backend %q", name)
+	}
+	return newBackend(), nil
+}
+
+func detectPasteBackend() string {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		for _, name := range []string{"wtype", "ydotool"} {
+			if _, err := exec.LookPath(name); err == nil {
+				return name
+			}
+		}
+		return "portal"
+	}
+	return "xdotool"
+}
+
+type commandPasteBackend []string
+
+func (backend commandPasteBackend) paste(done func(err error)) {
+	go func() {
+		output, err := exec.Command(backend[0], backend[1:]...).CombinedOutput()
+		if err != nil && len(output) > 0 {
+			err = fmt.Errorf("%s: %v: %s", backend[0], err, strings.TrimSpace(string(output)))
+		}
+		glib.IdleAdd(func() {
+			done(err)
+		})
+	}()
+}
+
+type fakePasteBackend struct {
+	pastes int
+}
+
+func (backend *fakePasteBackend) paste(done func(err error)) {
+	backend.pastes++
+	log.Printf("Paste %d sent to fake backend", backend.pastes)
+	done(nil)
+}
+
+type portalPasteBackend struct {
+	portal  *Portal
+	session string
+}
+
+func (backend *portalPasteBackend) paste(done func(err error)) {
+	if backend.session != "" {
+		backend.sendKeys(done)
+		return
+	}
+
+	if backend.portal == nil {
+		portal, err := sessionPortal()
+		if err != nil {
+			done(err)
+			return
+		}
+		backend.portal = portal
+	}
+
+	backend.startSession(func(err error) {
+		if err != nil {
+			done(err)
+			return
+		}
+		backend.sendKeys(done)
+	})
+}
+
+func (backend *portalPasteBackend) restoreTokenPath() string {
+	return app.dataDir + "/remote-desktop-token"
+}
+
+func (backend *portalPasteBackend) startSession(done func(err error)) {
+	portal := backend.portal
+	options := map[string]*glib.Variant{"session_handle_token": glib.NewVariantString(portal.token())}
+	portal.request(remoteDesktopInterface, "CreateSession", nil, options, func(results *glib.VariantDict, err error) {
+		if err != nil {
+			done(err)
+			return
+		}
+		sessionHandle := results.LookupValue("session_handle", glib.NewVariantType("s"))
+		if sessionHandle == nil {
+			done(fmt.Errorf("portal did not return a session"))
+			return
+		}
+		session := glib.NewVariantObjectPath(sessionHandle.String())
+
+		options := map[string]*glib.Variant{
+			"types":        glib.NewVariantUint32(remoteDesktopKeyboard),
+			"persist_mode": glib.NewVariantUint32(remoteDesktopPersist),
+		}
+		if token, err := os.ReadFile(backend.restoreTokenPath()); err == nil && len(token) > 0 {
+			options["restore_token"] = glib.NewVariantString(strings.TrimSpace(string(token)))
+		}
+		portal.request(remoteDesktopInterface, "SelectDevices", []*glib.Variant{session}, options, func(results *glib.VariantDict, err error) {
+			if err != nil {
+				done(err)
+				return
+			}
+			portal.request(remoteDesktopInterface, "Start", []*glib.Variant{session, glib.NewVariantString("")}, nil, func(results *glib.VariantDict, err error) {
+				if err != nil {
+					done(err)
+					return
+				}
+				if token := results.LookupValue("restore_token", glib.NewVariantType("s")); token != nil {
+					os.WriteFile(backend.restoreTokenPath(), []byte(token.String()), 0600)
+				}
+				backend.session = sessionHandle.String()
+				done(nil)
+			})
+		})
+	})
+}
+
+func (backend *portalPasteBackend) sendKeys(done func(err error)) {
+	keys := []struct {
+		keysym int32
+		state  uint32
+	}{
+		{keysymControlL, 1},
+		{keysymV, 1},
+		{keysymV, 0},
+		{keysymControlL, 0},
+	}
+
+	var send func(index int)
+	send = func(index int) {
+		if index == len(keys) {
+			done(nil)
+			return
+		}
+		parameters := glib.NewVariantTuple([]*glib.Variant{
+			glib.NewVariantObjectPath(backend.session),
+			portalOptions(nil),
+			glib.NewVariantInt32(keys[index].keysym),
+			glib.NewVariantUint32(keys[index].state),
+		})
+		backend.portal.call(remoteDesktopInterface, "NotifyKeyboardKeysym", parameters, func(result *glib.Variant, err error) {
+			if err != nil {
+				backend.session = ""
+				done(err)
+				return
+			}
+			send(index + 1)
+		})
+	}
+	send(0)
+}
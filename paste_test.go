@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+// testAutoPaste runs autoPaste with the fake backend until it is done and
+// returns the calls in order.
+func testAutoPaste(t *testing.T, enabled bool) ([]string, *fakePasteBackend) {
+	t.Helper()
+	saved := config
+	config.AutoPaste = enabled
+	config.PasteDelayMS = 0
+	backend := &fakePasteBackend{}
+	pasteBackend = backend
+	t.Cleanup(func() {
+		config = saved
+		pasteBackend = nil
+	})
+
+	var calls []string
+	done := false
+	autoPaste(func() {
+		calls = append(calls, "hide")
+	}, func() {
+		calls = append(calls, "done")
+		done = true
+	})
+	for !done {
+		glib.MainContextDefault().Iteration(true)
+	}
+	return calls, backend
+}
+
+func TestAutoPaste(t *testing.T) {
+	calls, backend := testAutoPaste(t, true)
+	if backend.pastes != 1 {
+		t.Errorf("sent %d pastes, want 1", backend.pastes)
+	}
+	if len(calls) != 2 || calls[0] != "hide" || calls[1] != "done" {
+		t.Errorf("calls are %v, want [hide done]", calls)
+	}
+}
+
+func TestAutoPasteDisabled(t *testing.T) {
+	calls, backend := testAutoPaste(t, false)
+	if backend.pastes != 0 {
+		t.Errorf("sent %d pastes while disabled", backend.pastes)
+	}
+	if len(calls) != 1 || calls[0] != "done" {
+		t.Errorf("calls are %v, want [done]", calls)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+const popupItemLimit = 30
+
+type Popup struct {
+	window      *gtk.ApplicationWindow
+	searchEntry *gtk.SearchEntry
+	list        *gtk.ListBox
+	listScroll  *gtk.ScrolledWindow
+	errorLabel  *gtk.Label
+	pasting     bool
+}
+
+func (popup *Popup) init() {
+	gtkApp := gtk.NewApplication(app.id+"-popup", gio.ApplicationDefaultFlags)
+	gtkApp.ConnectActivate(func() { popup.activate(gtkApp) })
+
+	if code := gtkApp.Run(nil); code > 0 {
+		os.Exit(code)
+	}
+}
+
+func (popup *Popup) activate(gtkApp *gtk.Application) {
+	if popup.window != nil {
+		popup.window.Close()
+		return
+	}
+
+	gui.setupCSS()
+
+	popup.window = gtk.NewApplicationWindow(gtkApp)
+	popup.window.SetTitle(app.name)
+	popup.window.SetDecorated(false)
+	popup.window.SetResizable(false)
+	popup.window.SetDefaultSize(420, 360)
+	popup.window.AddCSSClass("popup")
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(6)
+	box.SetMarginBottom(6)
+	box.SetMarginStart(6)
+	box.SetMarginEnd(6)
+
+	popup.searchEntry = gtk.NewSearchEntry()
+	popup.searchEntry.SetPlaceholderText("Search or /regex/")
+	box.Append(popup.searchEntry)
+
+	popup.errorLabel = gtk.NewLabel("")
+	popup.errorLabel.SetXAlign(0)
+	popup.errorLabel.SetWrap(true)
+	popup.errorLabel.AddCSSClass("search-error")
+	popup.errorLabel.SetVisible(false)
+	box.Append(popup.errorLabel)
+
+	popup.list = gtk.NewListBox()
+	popup.list.SetSelectionMode(gtk.SelectionBrowse)
+	popup.list.SetCanFocus(false)
+	popup.listScroll = gtk.NewScrolledWindow()
+	popup.listScroll.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	popup.listScroll.SetVExpand(true)
+	popup.listScroll.SetChild(popup.list)
+	box.Append(popup.listScroll)
+
+	popup.window.SetChild(box)
+	popup.setupEvents()
+	popup.updateRows()
+
+	popup.window.SetVisible(true)
+	popup.searchEntry.GrabFocus()
+}
+
+func (popup *Popup) setupEvents() {
+	popup.searchEntry.ConnectSearchChanged(popup.updateRows)
+	popup.searchEntry.ConnectActivate(func() {
+		popup.copyRow(popup.list.SelectedRow())
+	})
+	popup.searchEntry.ConnectStopSearch(func() {
+		popup.window.Close()
+	})
+	popup.list.ConnectRowActivated(popup.copyRow)
+
+	keyController := gtk.NewEventControllerKey()
+	keyController.SetPropagationPhase(gtk.PhaseCapture)
+	keyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		switch {
+		case keyval == gdk.KEY_Escape:
+			popup.window.Close()
+			return true
+		case state&gdk.AltMask != 0 && keyval >= gdk.KEY_1 && keyval <= gdk.KEY_9:
+			popup.copyRow(popup.list.RowAtIndex(int(keyval - gdk.KEY_1)))
+			return true
+		case keyval == gdk.KEY_Down || keyval == gdk.KEY_KP_Down:
+			popup.moveSelection(1)
+			return true
+		case keyval == gdk.KEY_Up || keyval == gdk.KEY_KP_Up:
+			popup.moveSelection(-1)
+			return true
+		}
+		return false
+	})
+	popup.window.AddController(keyController)
+
+	popup.window.NotifyProperty("is-active", func() {
+		if !popup.window.IsActive() && !popup.pasting {
+			popup.window.Close()
+		}
+	})
+}
+
+func (popup *Popup) updateRows() {
+	popup.list.RemoveAll()
+
+	filter := popup.searchEntry.Text()
+	if err := validateSearchFilter(filter); err != nil {
+		popup.errorLabel.SetText(err.Error())
+		popup.errorLabel.SetVisible(true)
+		return
+	}
+	popup.errorLabel.SetVisible(false)
+
+	items, err := clipboard.sortedSearch(filter, popupItemLimit, config.SortMode)
+	if err != nil {
+		popup.errorLabel.SetText(err.Error())
+		popup.errorLabel.SetVisible(true)
+		return
+	}
+
+	for i, item := range items {
+		popup.addRow(i, item)
+	}
+	if row := popup.list.RowAtIndex(0); row != nil {
+		popup.list.SelectRow(row)
+	}
+}
+
+func (popup *Popup) addRow(index int, item ClipboardItem) {
+	rowBox := gtk.NewBox(gtk.OrientationHorizontal, 8)
+	rowBox.SetMarginTop(6)
+	rowBox.SetMarginBottom(6)
+	rowBox.SetMarginStart(6)
+	rowBox.SetMarginEnd(6)
+
+	numberLabel := gtk.NewLabel("")
+	if index < 9 {
+		numberLabel.SetText(strconv.Itoa(index + 1))
+	}
+	numberLabel.SetWidthChars(1)
+	numberLabel.AddCSSClass("dim-label")
+	rowBox.Append(numberLabel)
+
+	icon := gtk.NewImageFromIconName(kindIcons[item.kind])
+	icon.AddCSSClass("item-kind")
+	rowBox.Append(icon)
+
+	content := strings.Join(strings.Fields(item.content), " ")
+	if item.itemType == 2 {
+		content = "Image · " + item.dateTime
+	}
+	contentLabel := gtk.NewLabel(content)
+	contentLabel.SetXAlign(0)
+	contentLabel.SetHExpand(true)
+	contentLabel.SetEllipsize(pango.EllipsizeEnd)
+	contentLabel.SetSingleLineMode(true)
+	rowBox.Append(contentLabel)
+
+	row := gtk.NewListBoxRow()
+	row.SetName(strconv.Itoa(item.id))
+	row.SetChild(rowBox)
+	popup.list.Append(row)
+}
+
+func (popup *Popup) moveSelection(step int) {
+	selectedRow := popup.list.SelectedRow()
+	if selectedRow == nil {
+		return
+	}
+	if row := popup.list.RowAtIndex(selectedRow.Index() + step); row != nil {
+		popup.list.SelectRow(row)
+		if bounds, ok := row.ComputeBounds(popup.list); ok {
+			adjustment := popup.listScroll.VAdjustment()
+			adjustment.ClampPage(float64(bounds.Y()), float64(bounds.Y()+bounds.Height()))
+		}
+	}
+}
+
+func (popup *Popup) copyRow(row *gtk.ListBoxRow) {
+	if row == nil {
+		return
+	}
+
+	if err := ipc.request(IPCRequest{Command: "copy", ID: row.Name()}); err != nil {
+		popup.errorLabel.SetText(err.Error())
+		popup.errorLabel.SetVisible(true)
+		return
+	}
+
+	popup.pasting = true
+	autoPaste(func() {
+		popup.window.SetVisible(false)
+	}, popup.window.Close)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const (
+	portalBusName          = "org.freedesktop.portal.Desktop"
+	portalObjectPath       = "/org/freedesktop/portal/desktop"
+	portalRequestInterface = "org.freedesktop.portal.Request"
+	portalCallTimeout      = 30000
+)
+
+type Portal struct {
+	connection *gio.DBusConnection
+	busName    string
+	objectPath string
+	tokens     int
+}
+
+func newPortal(connection *gio.DBusConnection) *Portal {
+	return &Portal{connection: connection, busName: portalBusName, objectPath: portalObjectPath}
+}
+
+func sessionPortal() (*Portal, error) {
+	connection, err := gio.BusGetSync(context.Background(), gio.BusTypeSession)
+	if err != nil {
+		return nil, err
+	}
+	return newPortal(connection), nil
+}
+
+func (portal *Portal) token() string {
+	portal.tokens++
+	return "clyp" + strconv.Itoa(portal.tokens)
+}
+
+func (portal *Portal) requestPath(token string) string {
+	sender := strings.ReplaceAll(strings.TrimPrefix(portal.connection.UniqueName(), ":"), ".", "_")
+	return portal.objectPath + "/request/" + sender + "/" + token
+}
+
+func (portal *Portal) call(interfaceName, method string, parameters *glib.Variant, callback func(result *glib.Variant, err error)) {
+	portal.connection.Call(context.Background(), portal.busName, portal.objectPath, interfaceName, method, parameters, nil, gio.DBusCallFlagsNone, portalCallTimeout, func(res gio.AsyncResulter) {
+		result, err := portal.connection.CallFinish(res)
+		if callback != nil {
+			callback(result, err)
+		}
+	})
+}
+
+func (portal *Portal) request(interfaceName, method string, arguments []*glib.Variant, options map[string]*glib.Variant, callback func(results *glib.VariantDict, err error)) {
+	token := portal.token()
+	if options == nil {
+		options = map[string]*glib.Variant{}
+	}
+	options["handle_token"] = glib.NewVariantString(token)
+
+	var subscription uint
+	subscription = portal.connection.SignalSubscribe(portal.busName, portalRequestInterface, "Response", portal.requestPath(token), "", gio.DBusSignalFlagsNone, func(connection *gio.DBusConnection, senderName, objectPath, signalInterface, signalName string, parameters *glib.Variant) {
+		portal.connection.SignalUnsubscribe(subscription)
+		switch response := parameters.ChildValue(0).Uint32(); response {
+		case 0:
+			callback(glib.NewVariantDict(parameters.ChildValue(1)), nil)
+		case 1:
+			callback(nil, fmt.Errorf("%s.%s was cancelled", interfaceName, method))
+		default:
+			callback(nil, fmt.Errorf("%s.%s failed", interfaceName, method))
+		}
+	})
+
+	parameters := glib.NewVariantTuple(append(arguments, portalOptions(options)))
+	portal.call(interfaceName, method, parameters, func(result *glib.Variant, err error) {
+		if err != nil {
+			portal.connection.SignalUnsubscribe(subscription)
+			callback(nil, err)
+		}
+	})
+}
+
+func portalOptions(options map[string]*glib.Variant) *glib.Variant {
+	entries := make([]*glib.Variant, 0, len(options))
+	for key, value := range options {
+		entries = append(entries, glib.NewVariantDictEntry(glib.NewVariantString(key), glib.NewVariantVariant(value)))
+	}
+	return glib.NewVariantArray(glib.NewVariantType("{sv}"), entries)
+}
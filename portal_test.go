@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	coreglib "github.com/diamondburned/gotk4/pkg/core/glib"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const testPortalInterface = "org.freedesktop.portal.Test"
+
+const testPortalIntrospection = `<node>
+  <interface name="org.freedesktop.portal.Test">
+    <method name="Start">
+      <arg name="response" type="u" direction="in"/>
+      <arg name="options" type="a{sv}" direction="in"/>
+      <arg name="handle" type="o" direction="out"/>
+    </method>
+  </interface>
+</node>`
+
+// testPortal serves a portal on the service connection whose Start method
+// answers on the request object with the given response code.
+func testPortal(t *testing.T, service *gio.DBusConnection) {
+	t.Helper()
+	nodeInfo, err := gio.NewDBusNodeInfoForXML(testPortalIntrospection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handleMethodCall := func(connection *gio.DBusConnection, sender, objectPath, interfaceName, methodName string, parameters *glib.Variant, invocation *gio.DBusMethodInvocation) {
+		response := parameters.ChildValue(0).Uint32()
+		token := glib.NewVariantDict(parameters.ChildValue(1)).LookupValue("handle_token", glib.NewVariantType("s"))
+		if token == nil {
+			invocation.ReturnDBusError(testPortalInterface+".Error", "handle_token is missing")
+			return
+		}
+		handle := portalObjectPath + "/request/" + strings.ReplaceAll(strings.TrimPrefix(sender, ":"), ".", "_") + "/" + token.String()
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{glib.NewVariantObjectPath(handle)}))
+
+		results := portalOptions(map[string]*glib.Variant{"token": token})
+		err := connection.EmitSignal(sender, handle, portalRequestInterface, "Response", glib.NewVariantTuple([]*glib.Variant{glib.NewVariantUint32(response), results}))
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	getProperty := func(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string) *coreglib.Value {
+		return nil
+	}
+	setProperty := func(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string, value *glib.Variant) bool {
+		return false
+	}
+	if _, err := service.RegisterObject(portalObjectPath, nodeInfo.LookupInterface(testPortalInterface), handleMethodCall, getProperty, setProperty); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testRequest sends a request and runs the default main context until the
+// response arrives.
+func testRequest(portal *Portal, response uint32) (*glib.VariantDict, error) {
+	var results *glib.VariantDict
+	var err error
+	done := false
+	portal.request(testPortalInterface, "Start", []*glib.Variant{glib.NewVariantUint32(response)}, nil, func(requestResults *glib.VariantDict, requestErr error) {
+		results, err = requestResults, requestErr
+		done = true
+	})
+	for !done {
+		glib.MainContextDefault().Iteration(true)
+	}
+	return results, err
+}
+
+func TestPortalRequest(t *testing.T) {
+	service, client := testBus(t)
+	testPortal(t, service)
+	portal := newPortal(client)
+	portal.busName = service.UniqueName()
+
+	results, err := testRequest(portal, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := results.LookupValue("token", glib.NewVariantType("s"))
+	if token == nil || token.String() != "clyp1" {
+		t.Errorf("response token is %v, want clyp1", token)
+	}
+
+	if _, err := testRequest(portal, 1); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("cancelled request returned %v", err)
+	}
+	if _, err := testRequest(portal, 2); err == nil {
+		t.Error("failed request returned no error")
+	}
+}
+
+func TestPortalRequestUnknownMethod(t *testing.T) {
+	service, client := testBus(t)
+	portal := newPortal(client)
+	portal.busName = service.UniqueName()
+
+	if _, err := testRequest(portal, 0); err == nil {
+		t.Error("request to a missing portal returned no error")
+	}
+}
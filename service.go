@@ -1,10 +1,12 @@
 package main
 
 import (
+	"log"
 	"os"
 
 	_ "github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
@@ -20,8 +22,69 @@ func (service *Service) init() {
 }
 
 func (service *Service) activate(gtkServiceApp *gtk.Application) {
+	notifier.init(gtkServiceApp, openMainWindow)
+	if err := database.checkIntegrity(); err != nil {
+		log.Printf("Failed to open database: %v", err)
+	}
 	database.vacuum()
-	clipboard.updateRecentContentFromDatabase()
+	clipboard.updateRecentHashFromDatabase()
 	clipboard.watch()
+	go ipc.listenWatcher()
+	api.listen()
+	service.registerShortcuts()
+	if connection := gtkServiceApp.DBusConnection(); connection != nil {
+		if err := dbus.register(connection, gtkServiceApp.DBusObjectPath()); err != nil {
+			log.Printf("Failed to export D-Bus interface: %v", err)
+		}
+		if config.TrayIcon {
+			if err := tray.register(connection); err != nil {
+				log.Printf("Failed to export tray icon: %v", err)
+				notifier.error("Failed to export tray icon: %v", err)
+			}
+		}
+	}
+	service.scheduleBackups()
+	service.scheduleTrashPurge()
 	gtkServiceApp.Hold()
 }
+
+func (service *Service) scheduleBackups() {
+	if config.BackupCount <= 0 || config.BackupIntervalMinutes <= 0 {
+		return
+	}
+
+	database.backupIfDue()
+	glib.TimeoutSecondsAdd(uint(config.BackupIntervalMinutes*60), func() bool {
+		database.backupIfDue()
+		return true
+	})
+}
+
+func (service *Service) scheduleTrashPurge() {
+	if config.TrashDays <= 0 {
+		return
+	}
+
+	purge := func() bool {
+		if _, err := clipboard.purgeTrash(config.TrashDays); err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+			notifier.error("Failed to purge trash: %v", err)
+		}
+		return true
+	}
+	purge()
+	glib.TimeoutSecondsAdd(3600, purge)
+}
+
+func (service *Service) registerShortcuts() {
+	if !config.GlobalShortcuts {
+		return
+	}
+
+	portal, err := sessionPortal()
+	if err != nil {
+		log.Printf("Global shortcuts are not available: %v", err)
+		return
+	}
+	shortcuts.register(portal)
+}
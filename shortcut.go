@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const globalShortcutsInterface = "org.freedesktop.portal.GlobalShortcuts"
+
+type GlobalShortcut struct {
+	id          string
+	description string
+	trigger     string
+	activate    func()
+}
+
+type Shortcuts struct {
+	portal  *Portal
+	session string
+}
+
+var globalShortcuts = []GlobalShortcut{
+	{"show-popup", "Show the quick-paste popup", "CTRL+ALT+v", showPopup},
+	{"pause-capture", "Pause or resume clipboard capture", "CTRL+ALT+p", func() { clipboard.setPaused(!clipboard.paused) }},
+	{"copy-previous", "Copy the previous clipboard item", "", copyPreviousItem},
+}
+
+func (shortcuts *Shortcuts) register(portal *Portal) {
+	shortcuts.portal = portal
+	options := map[string]*glib.Variant{"session_handle_token": glib.NewVariantString(portal.token())}
+	portal.request(globalShortcutsInterface, "CreateSession", nil, options, func(results *glib.VariantDict, err error) {
+		if err != nil {
+			log.Printf("Global shortcuts are not available: %v", err)
+			return
+		}
+		sessionHandle := results.LookupValue("session_handle", glib.NewVariantType("s"))
+		if sessionHandle == nil {
+			log.Printf("Global shortcuts are not available: portal did not return a session")
+			return
+		}
+		shortcuts.session = sessionHandle.String()
+		shortcuts.subscribe()
+		shortcuts.bind()
+	})
+}
+
+func (shortcuts *Shortcuts) bind() {
+	entries := make([]*glib.Variant, 0, len(globalShortcuts))
+	for _, shortcut := range globalShortcuts {
+		options := map[string]*glib.Variant{"description": glib.NewVariantString(shortcut.description)}
+		trigger := shortcut.trigger
+		if configured, ok := config.ShortcutTriggers[shortcut.id]; ok {
+			trigger = configured
+		}
+		if trigger != "" {
+			options["preferred_trigger"] = glib.NewVariantString(trigger)
+		}
+		entries = append(entries, glib.NewVariantTuple([]*glib.Variant{glib.NewVariantString(shortcut.id), portalOptions(options)}))
+	}
+
+	arguments := []*glib.Variant{
+		glib.NewVariantObjectPath(shortcuts.session),
+		glib.NewVariantArray(glib.NewVariantType("(sa{sv})"), entries),
+		glib.NewVariantString(""),
+	}
+	shortcuts.portal.request(globalShortcutsInterface, "BindShortcuts", arguments, nil, func(results *glib.VariantDict, err error) {
+		if err != nil {
+			log.Printf("Failed to bind global shortcuts: %v", err)
+		}
+	})
+}
+
+func (shortcuts *Shortcuts) subscribe() {
+	portal := shortcuts.portal
+	portal.connection.SignalSubscribe(portal.busName, globalShortcutsInterface, "Activated", portal.objectPath, "", gio.DBusSignalFlagsNone, func(connection *gio.DBusConnection, senderName, objectPath, interfaceName, signalName string, parameters *glib.Variant) {
+		if parameters.ChildValue(0).String() != shortcuts.session {
+			return
+		}
+		shortcuts.activate(parameters.ChildValue(1).String())
+	})
+}
+
+func (shortcuts *Shortcuts) activate(id string) {
+	for _, shortcut := range globalShortcuts {
+		if shortcut.id == id {
+			shortcut.activate()
+			return
+		}
+	}
+}
+
+func showPopup() {
+	app.spawn("popup")
+}
+
+func copyPreviousItem() {
+	items, err := clipboard.search("", 2)
+	if err != nil || len(items) < 2 {
+		return
+	}
+	if err := clipboard.copy(strconv.Itoa(items[1].id)); err != nil {
+		log.Printf("Failed to copy previous item: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Snippets struct{}
+
+type Snippet struct {
+	id       int
+	name     string
+	content  string
+	dateTime string
+}
+
+var snippetPlaceholder = regexp.MustCompile(`\{\{\s*(date|time|datetime|clipboard|uuid|input)\s*(?::([^}]+))?\}\}`)
+
+func (snippets *Snippets) list() ([]Snippet, error) {
+	var items []Snippet
+
+	rows, err := database.db.Query("SELECT id, name, content, date_time FROM snippets ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snippet Snippet
+		if err := rows.Scan(&snippet.id, &snippet.name, &snippet.content, &snippet.dateTime); err != nil {
+			return nil, err
+		}
+		items = append(items, snippet)
+	}
+
+	return items, rows.Err()
+}
+
+// get finds a snippet by name, a numeric argument that is not a name is
+// looked up as an id.
+func (snippets *Snippets) get(name string) (Snippet, error) {
+	var snippet Snippet
+	row := database.db.QueryRow("SELECT id, name, content, date_time FROM snippets WHERE name=?", name)
+	err := row.Scan(&snippet.id, &snippet.name, &snippet.content, &snippet.dateTime)
+	if err == sql.ErrNoRows {
+		if id, convErr := strconv.Atoi(name); convErr == nil {
+			row = database.db.QueryRow("SELECT id, name, content, date_time FROM snippets WHERE id=?", id)
+			err = row.Scan(&snippet.id, &snippet.name, &snippet.content, &snippet.dateTime)
+		}
+	}
+	if err != nil {
+		return Snippet{}, fmt.Errorf("snippet %q not found", name)
+	}
+	return snippet, nil
+}
+
+func (snippets *Snippets) save(id int, name, content string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("snippet name is empty")
+	}
+	if content == "" {
+		return fmt.Errorf("snippet content is empty")
+	}
+
+	var err error
+	if id == 0 {
+		_, err = database.db.Exec("INSERT INTO snippets (name, content) VALUES (?, ?)", name, content)
+	} else {
+		_, err = database.db.Exec("UPDATE snippets SET name=?, content=? WHERE id=?", name, content, id)
+	}
+	return err
+}
+
+func (snippets *Snippets) remove(id int) error {
+	_, err := database.db.Exec("DELETE FROM snippets WHERE id=?", id)
+	return err
+}
+
+func (snippets *Snippets) inputs(content string) []string {
+	var names []string
+	for _, match := range snippetPlaceholder.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimSpace(match[2])
+		if match[1] == "input" && name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (snippets *Snippets) usesClipboard(content string) bool {
+	for _, match := range snippetPlaceholder.FindAllStringSubmatch(content, -1) {
+		if match[1] == "clipboard" {
+			return true
+		}
+	}
+	return false
+}
+
+func (snippets *Snippets) expand(content, clipboardText string, inputs map[string]string) string {
+	now := time.Now()
+
+	return snippetPlaceholder.ReplaceAllStringFunc(content, func(placeholder string) string {
+		match := snippetPlaceholder.FindStringSubmatch(placeholder)
+		switch match[1] {
+		case "date":
+			return now.Format(snippetTimeLayout(match[2], time.DateOnly))
+		case "time":
+			return now.Format(snippetTimeLayout(match[2], time.TimeOnly))
+		case "datetime":
+			return now.Format(snippetTimeLayout(match[2], time.DateTime))
+		case "clipboard":
+			return clipboardText
+		case "uuid":
+			return newUUID()
+		case "input":
+			return inputs[strings.TrimSpace(match[2])]
+		}
+		return placeholder
+	})
+}
+
+func snippetTimeLayout(layout, defaultLayout string) string {
+	if strings.TrimSpace(layout) == "" {
+		return defaultLayout
+	}
+	return layout
+}
+
+func newUUID() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
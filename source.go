@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sourceDetectTimeout = 500 * time.Millisecond
+
+type SourceApp struct {
+	app   string
+	title string
+	pid   int
+}
+
+var (
+	xpropWindowPattern = regexp.MustCompile(`window id # (0x[0-9a-fA-F]+)`)
+	xpropStringPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+)
+
+func (source SourceApp) ignored() bool {
+	for _, rule := range config.IgnoredApps {
+		if source.matches(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches compares a rule to the application id or WM_CLASS, ignoring case,
+// rules wrapped in slashes are regular expressions also matched to the title.
+func (source SourceApp) matches(rule string) bool {
+	if pattern, isRegex := parseSearchFilter(rule); isRegex {
+		for _, value := range []string{source.app, source.title} {
+			if value == "" {
+				continue
+			}
+			if matched, err := regexpMatch(pattern, value); err == nil && matched {
+				return true
+			}
+		}
+		return false
+	}
+	return source.app != "" && strings.EqualFold(rule, source.app)
+}
+
+func validateIgnoreRule(rule string) error {
+	if strings.TrimSpace(rule) == "" {
+		return fmt.Errorf("missing application")
+	}
+	return validateSearchFilter(rule)
+}
+
+func addIgnoredApp(rule string) error {
+	if err := validateIgnoreRule(rule); err != nil {
+		return err
+	}
+	if slices.Contains(config.IgnoredApps, rule) {
+		return nil
+	}
+	config.IgnoredApps = append(config.IgnoredApps, rule)
+	return saveIgnoredApps()
+}
+
+func removeIgnoredApp(rule string) error {
+	index := slices.Index(config.IgnoredApps, rule)
+	if index < 0 {
+		return fmt.Errorf("%q is not ignored", rule)
+	}
+	config.IgnoredApps = slices.Delete(config.IgnoredApps, index, index+1)
+	return saveIgnoredApps()
+}
+
+func saveIgnoredApps() error {
+	if err := config.save(); err != nil {
+		return err
+	}
+	// The watcher reads the config file again, it picks up the list on its next start otherwise.
+	ipc.request(IPCRequest{Command: "reload_config"})
+	return nil
+}
+
+func (source SourceApp) label() string {
+	if source.app == "" {
+		return source.title
+	}
+	return source.app
+}
+
+// detectSourceApp returns the owner of the clipboard on X11. Owners are not
+// exposed on Wayland, the focused window is used there, which is the window
+// content was copied from in almost all cases. It runs external commands and
+// must not be called on the main loop.
+func detectSourceApp() SourceApp {
+	var source SourceApp
+	switch {
+	case os.Getenv("SWAYSOCK") != "":
+		source = detectSwayWindow()
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		source = detectHyprlandWindow()
+	case os.Getenv("WAYLAND_DISPLAY") == "" && os.Getenv("DISPLAY") != "":
+		source = detectX11Owner()
+	}
+
+	if source.app == "" && source.pid > 0 {
+		if comm, err := os.ReadFile("/proc/" + strconv.Itoa(source.pid) + "/comm"); err == nil {
+			source.app = strings.TrimSpace(string(comm))
+		}
+	}
+	return source
+}
+
+func sourceCommandOutput(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sourceDetectTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+type swayNode struct {
+	AppID            *string `json:"app_id"`
+	Name             string  `json:"name"`
+	PID              int     `json:"pid"`
+	Focused          bool    `json:"focused"`
+	WindowProperties struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+func (node swayNode) focused() (swayNode, bool) {
+	if node.Focused {
+		return node, true
+	}
+	for _, child := range append(node.Nodes, node.FloatingNodes...) {
+		if focused, ok := child.focused(); ok {
+			return focused, true
+		}
+	}
+	return swayNode{}, false
+}
+
+func detectSwayWindow() SourceApp {
+	output, err := sourceCommandOutput("swaymsg", "-t", "get_tree", "--raw")
+	if err != nil {
+		return SourceApp{}
+	}
+	var tree swayNode
+	if err := json.Unmarshal(output, &tree); err != nil {
+		return SourceApp{}
+	}
+	node, ok := tree.focused()
+	if !ok || node.PID == 0 {
+		return SourceApp{}
+	}
+
+	source := SourceApp{app: node.WindowProperties.Class, title: node.Name, pid: node.PID}
+	if node.AppID != nil && *node.AppID != "" {
+		source.app = *node.AppID
+	}
+	return source
+}
+
+func detectHyprlandWindow() SourceApp {
+	output, err := sourceCommandOutput("hyprctl", "activewindow", "-j")
+	if err != nil {
+		return SourceApp{}
+	}
+	var window struct {
+		Class string `json:"class"`
+		Title string `json:"title"`
+		PID   int    `json:"pid"`
+	}
+	if err := json.Unmarshal(output, &window); err != nil {
+		return SourceApp{}
+	}
+	return SourceApp{app: window.Class, title: window.Title, pid: window.PID}
+}
+
+// detectX11Owner reads the window owning the clipboard, toolkits that own it
+// with a hidden window without a class or pid fall back to the active window.
+func detectX11Owner() SourceApp {
+	if owner := x11SelectionOwner(); owner != 0 {
+		if source := x11WindowSource(fmt.Sprintf("0x%x", owner)); source.app != "" || source.pid > 0 {
+			return source
+		}
+	}
+
+	output, err := sourceCommandOutput("xprop", "-root", "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return SourceApp{}
+	}
+	match := xpropWindowPattern.FindSubmatch(output)
+	if match == nil || string(match[1]) == "0x0" {
+		return SourceApp{}
+	}
+	return x11WindowSource(string(match[1]))
+}
+
+func x11WindowSource(window string) SourceApp {
+	output, err := sourceCommandOutput("xprop", "-id", window, "WM_CLASS", "_NET_WM_NAME", "_NET_WM_PID")
+	if err != nil {
+		return SourceApp{}
+	}
+
+	var source SourceApp
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		values := xpropStringPattern.FindAllStringSubmatch(value, -1)
+		switch {
+		case strings.HasPrefix(name, "WM_CLASS") && len(values) > 0:
+			// WM_CLASS holds the instance and the class name, the class is the application.
+			source.app = values[len(values)-1][1]
+		case strings.HasPrefix(name, "_NET_WM_NAME") && len(values) > 0:
+			source.title = values[0][1]
+		case strings.HasPrefix(name, "_NET_WM_PID"):
+			source.pid, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	return source
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	statsDays     = 14
+	statsTopItems = 10
+	statsRateDays = 30
+)
+
+type Stats struct {
+	texts        int
+	images       int
+	pinned       int
+	trashed      int
+	captures     int
+	copies       int
+	days         []DayStats
+	topItems     []ClipboardItem
+	databaseSize int64
+	captureRate  float64
+}
+
+type DayStats struct {
+	day    string
+	texts  int
+	images int
+}
+
+func (clipboard *Clipboard) stats() (Stats, error) {
+	var stats Stats
+
+	err := database.db.QueryRow(`SELECT
+	COUNT(*) FILTER (WHERE deleted_at IS NULL AND type = 1),
+	COUNT(*) FILTER (WHERE deleted_at IS NULL AND type = 2),
+	COUNT(*) FILTER (WHERE deleted_at IS NULL AND pinned = 1),
+	COUNT(*) FILTER (WHERE deleted_at IS NOT NULL),
+	IFNULL(SUM(use_count), 0),
+	IFNULL(SUM(copy_count), 0)
+FROM clipboard`).Scan(&stats.texts, &stats.images, &stats.pinned, &stats.trashed, &stats.captures, &stats.copies)
+	if err != nil {
+		return stats, err
+	}
+
+	since := time.Now().AddDate(0, 0, -statsDays).UTC().Format(time.DateTime)
+	rows, err := database.db.Query(`SELECT date(date_time, 'localtime') AS day, COUNT(*) FILTER (WHERE type = 1), COUNT(*) FILTER (WHERE type = 2)
+FROM clipboard WHERE deleted_at IS NULL AND date_time >= ? GROUP BY day ORDER BY day DESC`, since)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day DayStats
+		if err := rows.Scan(&day.day, &day.texts, &day.images); err != nil {
+			return stats, err
+		}
+		stats.days = append(stats.days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	if stats.topItems, err = clipboard.sortedSearch("", statsTopItems, sortFrequent); err != nil {
+		return stats, err
+	}
+
+	var recentCaptures int
+	rateSince := time.Now().AddDate(0, 0, -statsRateDays).UTC().Format(time.DateTime)
+	if err := database.db.QueryRow("SELECT COUNT(*) FROM clipboard WHERE date_time >= ?", rateSince).Scan(&recentCaptures); err != nil {
+		return stats, err
+	}
+	stats.captureRate = float64(recentCaptures) / statsRateDays
+
+	for _, suffix := range []string{"", "-wal"} {
+		if info, err := os.Stat(database.path() + suffix); err == nil {
+			stats.databaseSize += info.Size()
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+)
+
+func (clipboard *Clipboard) addTags(id int, tags []string) error {
+	tx, err := database.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO clipboard_tags (item_id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (clipboard *Clipboard) removeTags(id int, tags []string) error {
+	for _, tag := range tags {
+		if _, err := database.db.Exec("DELETE FROM clipboard_tags WHERE item_id=? AND tag=?", id, strings.TrimSpace(tag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (clipboard *Clipboard) tags(id int) ([]string, error) {
+	var tags []string
+
+	rows, err := database.db.Query("SELECT tag FROM clipboard_tags WHERE item_id=? ORDER BY tag", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (clipboard *Clipboard) allTags() (map[string]int, error) {
+	tags := map[string]int{}
+
+	rows, err := database.db.Query("SELECT tag, COUNT(*) FROM clipboard_tags WHERE item_id IN (SELECT id FROM clipboard WHERE deleted_at IS NULL) GROUP BY tag ORDER BY tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		var count int
+		if err := rows.Scan(&tag, &count); err != nil {
+			return nil, err
+		}
+		tags[tag] = count
+	}
+
+	return tags, rows.Err()
+}
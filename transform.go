@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type Transform struct {
+	name  string
+	label string
+	apply func(text string) (string, error)
+}
+
+var (
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]+>`)
+	whitespacePattern = regexp.MustCompile(`[ \t\f\v]+`)
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+var transforms = []Transform{
+	{"trim", "Trim", transformTrim},
+	{"normalize-whitespace", "Normalize Whitespace", transformNormalizeWhitespace},
+	{"upper", "UPPER CASE", transformUpper},
+	{"lower", "lower case", transformLower},
+	{"title", "Title Case", transformTitle},
+	{"strip-formatting", "Strip Formatting", transformStripFormatting},
+	{"url-encode", "URL Encode", transformURLEncode},
+	{"url-decode", "URL Decode", transformURLDecode},
+	{"clean-url", "Clean URL", transformCleanURL},
+	{"base64-encode", "Base64 Encode", transformBase64Encode},
+	{"base64-decode", "Base64 Decode", transformBase64Decode},
+	{"json-pretty", "JSON Pretty Print", transformJSONPretty},
+	{"json-minify", "JSON Minify", transformJSONMinify},
+	{"escape-shell", "Escape for Shell", transformEscapeShell},
+	{"escape-json", "Escape for JSON", transformEscapeJSON},
+	{"escape-sql", "Escape for SQL", transformEscapeSQL},
+	{"sort-lines", "Sort Lines", transformSortLines},
+	{"dedupe-lines", "Remove Duplicate Lines", transformDedupeLines},
+	{"join-lines", "Join Lines", transformJoinLines},
+}
+
+func findTransform(name string) (Transform, error) {
+	for _, transform := range transforms {
+		if transform.name == name {
+			return transform, nil
+		}
+	}
+	return Transform{}, fmt.Errorf("unknown transform %q", name)
+}
+
+func applyTransforms(text string, names []string) (string, error) {
+	for _, name := range names {
+		transform, err := findTransform(name)
+		if err != nil {
+			return "", err
+		}
+		text, err = transform.apply(text)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", transform.name, err)
+		}
+	}
+	return text, nil
+}
+
+func transformTrim(text string) (string, error) {
+	return strings.TrimSpace(text), nil
+}
+
+func transformNormalizeWhitespace(text string) (string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(whitespacePattern.ReplaceAllString(line, " "))
+	}
+	text = blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text), nil
+}
+
+func transformUpper(text string) (string, error) {
+	return strings.ToUpper(text), nil
+}
+
+func transformLower(text string) (string, error) {
+	return strings.ToLower(text), nil
+}
+
+func transformTitle(text string) (string, error) {
+	runes := []rune(strings.ToLower(text))
+	for i, char := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '-' {
+			runes[i] = unicode.ToTitle(char)
+		}
+	}
+	return string(runes), nil
+}
+
+func transformStripFormatting(text string) (string, error) {
+	text = ansiEscapePattern.ReplaceAllString(text, "")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	return strings.Map(func(char rune) rune {
+		switch {
+		case char == '\u00a0' || char == '\u202f':
+			return ' '
+		case char == '\u200b' || char == '\u200c' || char == '\u200d' || char == '\ufeff':
+			return -1
+		case char == '\n' || char == '\t':
+			return char
+		case unicode.IsControl(char):
+			return -1
+		}
+		return char
+	}, text), nil
+}
+
+func transformURLEncode(text string) (string, error) {
+	return url.QueryEscape(text), nil
+}
+
+func transformURLDecode(text string) (string, error) {
+	return url.QueryUnescape(text)
+}
+
+func transformCleanURL(text string) (string, error) {
+	return cleanURL(strings.TrimSpace(text)), nil
+}
+
+func transformBase64Encode(text string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(text)), nil
+}
+
+func transformBase64Decode(text string) (string, error) {
+	text = strings.Join(strings.Fields(text), "")
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(text); err == nil {
+			return string(decoded), nil
+		}
+	}
+	return "", fmt.Errorf("input is not valid base64")
+}
+
+func transformJSONPretty(text string) (string, error) {
+	var buffer bytes.Buffer
+	if err := json.Indent(&buffer, []byte(text), "", "  "); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func transformJSONMinify(text string) (string, error) {
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, []byte(text)); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func transformEscapeShell(text string) (string, error) {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'", nil
+}
+
+func transformEscapeJSON(text string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(text); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+func transformEscapeSQL(text string) (string, error) {
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'", nil
+}
+
+func transformSortLines(text string) (string, error) {
+	lines := strings.Split(text, "\n")
+	slices.Sort(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+func transformDedupeLines(text string) (string, error) {
+	var lines []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func transformJoinLines(text string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " "), nil
+}
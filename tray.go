@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	coreglib "github.com/diamondburned/gotk4/pkg/core/glib"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+const (
+	trayItemInterface   = "org.kde.StatusNotifierItem"
+	trayItemPath        = "/StatusNotifierItem"
+	trayMenuInterface   = "com.canonical.dbusmenu"
+	trayMenuPath        = "/MenuBar"
+	trayWatcherName     = "org.kde.StatusNotifierWatcher"
+	trayWatcherPath     = "/StatusNotifierWatcher"
+	trayIconName        = "bio.murat.clyp"
+	trayPausedIconName  = "media-playback-pause"
+	trayRecentItemCount = 10
+	trayLabelLength     = 40
+)
+
+const (
+	trayMenuPause = iota + 100
+	trayMenuOpen
+	trayMenuClear
+	trayMenuSeparator
+	trayMenuEmptyHistory
+)
+
+const trayIntrospection = `<node>
+  <interface name="org.kde.StatusNotifierItem">
+    <method name="Activate">
+      <arg name="x" type="i" direction="in"/>
+      <arg name="y" type="i" direction="in"/>
+    </method>
+    <method name="SecondaryActivate">
+      <arg name="x" type="i" direction="in"/>
+      <arg name="y" type="i" direction="in"/>
+    </method>
+    <method name="ContextMenu">
+      <arg name="x" type="i" direction="in"/>
+      <arg name="y" type="i" direction="in"/>
+    </method>
+    <method name="Scroll">
+      <arg name="delta" type="i" direction="in"/>
+      <arg name="orientation" type="s" direction="in"/>
+    </method>
+    <property name="Category" type="s" access="read"/>
+    <property name="Id" type="s" access="read"/>
+    <property name="Title" type="s" access="read"/>
+    <property name="Status" type="s" access="read"/>
+    <property name="IconName" type="s" access="read"/>
+    <property name="IconThemePath" type="s" access="read"/>
+    <property name="ToolTip" type="(sa(iiay)ss)" access="read"/>
+    <property name="ItemIsMenu" type="b" access="read"/>
+    <property name="Menu" type="o" access="read"/>
+    <signal name="NewIcon"/>
+    <signal name="NewToolTip"/>
+    <signal name="NewStatus">
+      <arg name="status" type="s"/>
+    </signal>
+  </interface>
+  <interface name="com.canonical.dbusmenu">
+    <method name="GetLayout">
+      <arg name="parentId" type="i" direction="in"/>
+      <arg name="recursionDepth" type="i" direction="in"/>
+      <arg name="propertyNames" type="as" direction="in"/>
+      <arg name="revision" type="u" direction="out"/>
+      <arg name="layout" type="(ia{sv}av)" direction="out"/>
+    </method>
+    <method name="GetGroupProperties">
+      <arg name="ids" type="ai" direction="in"/>
+      <arg name="propertyNames" type="as" direction="in"/>
+      <arg name="properties" type="a(ia{sv})" direction="out"/>
+    </method>
+    <method name="GetProperty">
+      <arg name="id" type="i" direction="in"/>
+      <arg name="name" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="Event">
+      <arg name="id" type="i" direction="in"/>
+      <arg name="eventId" type="s" direction="in"/>
+      <arg name="data" type="v" direction="in"/>
+      <arg name="timestamp" type="u" direction="in"/>
+    </method>
+    <method name="EventGroup">
+      <arg name="events" type="a(isvu)" direction="in"/>
+      <arg name="idErrors" type="ai" direction="out"/>
+    </method>
+    <method name="AboutToShow">
+      <arg name="id" type="i" direction="in"/>
+      <arg name="needUpdate" type="b" direction="out"/>
+    </method>
+    <method name="AboutToShowGroup">
+      <arg name="ids" type="ai" direction="in"/>
+      <arg name="updatesNeeded" type="ai" direction="out"/>
+      <arg name="idErrors" type="ai" direction="out"/>
+    </method>
+    <property name="Version" type="u" access="read"/>
+    <property name="TextDirection" type="s" access="read"/>
+    <property name="Status" type="s" access="read"/>
+    <property name="IconThemePath" type="as" access="read"/>
+    <signal name="LayoutUpdated">
+      <arg name="revision" type="u"/>
+      <arg name="parent" type="i"/>
+    </signal>
+    <signal name="ItemsPropertiesUpdated">
+      <arg name="updatedProps" type="a(ia{sv})"/>
+      <arg name="removedProps" type="a(ias)"/>
+    </signal>
+  </interface>
+</node>`
+
+type Tray struct {
+	connection  *gio.DBusConnection
+	revision    uint32
+	recentItems []int
+}
+
+type trayMenuItem struct {
+	id         int32
+	properties map[string]*glib.Variant
+}
+
+func (tray *Tray) register(connection *gio.DBusConnection) error {
+	nodeInfo, err := gio.NewDBusNodeInfoForXML(trayIntrospection)
+	if err != nil {
+		return err
+	}
+
+	_, err = connection.RegisterObject(trayItemPath, nodeInfo.LookupInterface(trayItemInterface), tray.handleItemCall, tray.getItemProperty, tray.setProperty)
+	if err != nil {
+		return err
+	}
+	_, err = connection.RegisterObject(trayMenuPath, nodeInfo.LookupInterface(trayMenuInterface), tray.handleMenuCall, tray.getMenuProperty, tray.setProperty)
+	if err != nil {
+		return err
+	}
+	tray.connection = connection
+
+	connection.SignalSubscribe("org.freedesktop.DBus", "org.freedesktop.DBus", "NameOwnerChanged", "/org/freedesktop/DBus", trayWatcherName, gio.DBusSignalFlagsNone, func(connection *gio.DBusConnection, senderName, objectPath, interfaceName, signalName string, parameters *glib.Variant) {
+		if parameters.ChildValue(2).String() != "" {
+			tray.registerWithWatcher()
+		}
+	})
+	tray.registerWithWatcher()
+
+	return nil
+}
+
+func (tray *Tray) registerWithWatcher() {
+	parameters := glib.NewVariantTuple([]*glib.Variant{glib.NewVariantString(tray.connection.UniqueName())})
+	tray.connection.Call(context.Background(), trayWatcherName, trayWatcherPath, trayWatcherName, "RegisterStatusNotifierItem", parameters, nil, gio.DBusCallFlagsNone, -1, func(res gio.AsyncResulter) {
+		if _, err := tray.connection.CallFinish(res); err != nil {
+			log.Printf("Tray icon is not available: %v", err)
+		}
+	})
+}
+
+func (tray *Tray) setProperty(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string, value *glib.Variant) bool {
+	return false
+}
+
+func (tray *Tray) handleItemCall(connection *gio.DBusConnection, sender, objectPath, interfaceName, methodName string, parameters *glib.Variant, invocation *gio.DBusMethodInvocation) {
+	if methodName == "Activate" {
+		openMainWindow()
+	}
+	invocation.ReturnValue(nil)
+}
+
+func (tray *Tray) getItemProperty(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string) *coreglib.Value {
+	var value *glib.Variant
+	switch propertyName {
+	case "Category":
+		value = glib.NewVariantString("ApplicationStatus")
+	case "Id":
+		value = glib.NewVariantString("clyp")
+	case "Title":
+		value = glib.NewVariantString(app.name)
+	case "Status":
+		value = glib.NewVariantString("Active")
+	case "IconName":
+		value = glib.NewVariantString(tray.iconName())
+	case "ToolTip":
+		value = glib.NewVariantTuple([]*glib.Variant{
+			glib.NewVariantString(tray.iconName()),
+			glib.NewVariantArray(glib.NewVariantType("(iiay)"), nil),
+			glib.NewVariantString(app.name),
+			glib.NewVariantString(tray.description()),
+		})
+	case "ItemIsMenu":
+		value = glib.NewVariantBoolean(false)
+	case "Menu":
+		value = glib.NewVariantObjectPath(trayMenuPath)
+	default:
+		value = glib.NewVariantString("")
+	}
+	return glib.NewVariantValue(value)
+}
+
+func (tray *Tray) iconName() string {
+	if clipboard.paused {
+		return trayPausedIconName
+	}
+	return trayIconName
+}
+
+func (tray *Tray) description() string {
+	clipboard.count()
+	if clipboard.paused {
+		return fmt.Sprintf("Capture paused, %d items", clipboard.itemCount)
+	}
+	return fmt.Sprintf("%d items", clipboard.itemCount)
+}
+
+func (tray *Tray) getMenuProperty(connection *gio.DBusConnection, sender, objectPath, interfaceName, propertyName string) *coreglib.Value {
+	var value *glib.Variant
+	switch propertyName {
+	case "Version":
+		value = glib.NewVariantUint32(3)
+	case "TextDirection":
+		value = glib.NewVariantString("ltr")
+	case "Status":
+		value = glib.NewVariantString("normal")
+	default:
+		value = glib.NewVariantStrv([]string{})
+	}
+	return glib.NewVariantValue(value)
+}
+
+func (tray *Tray) handleMenuCall(connection *gio.DBusConnection, sender, objectPath, interfaceName, methodName string, parameters *glib.Variant, invocation *gio.DBusMethodInvocation) {
+	switch methodName {
+	case "GetLayout":
+		items := tray.menuItems()
+		children := make([]*glib.Variant, len(items))
+		for i, item := range items {
+			children[i] = glib.NewVariantVariant(trayLayout(item.id, item.properties, nil))
+		}
+		root := map[string]*glib.Variant{"children-display": glib.NewVariantString("submenu")}
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{
+			glib.NewVariantUint32(tray.revision),
+			trayLayout(0, root, children),
+		}))
+	case "GetGroupProperties":
+		requested := trayIDs(parameters.ChildValue(0))
+		var entries []*glib.Variant
+		for _, item := range tray.menuItems() {
+			if len(requested) > 0 && !requested[item.id] {
+				continue
+			}
+			entries = append(entries, glib.NewVariantTuple([]*glib.Variant{glib.NewVariantInt32(item.id), portalOptions(item.properties)}))
+		}
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{glib.NewVariantArray(glib.NewVariantType("(ia{sv})"), entries)}))
+	case "GetProperty":
+		id := parameters.ChildValue(0).Int32()
+		name := parameters.ChildValue(1).String()
+		for _, item := range tray.menuItems() {
+			if value, ok := item.properties[name]; ok && item.id == id {
+				invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{glib.NewVariantVariant(value)}))
+				return
+			}
+		}
+		invocation.ReturnDBusError(trayMenuInterface+".Error", "unknown property")
+	case "Event":
+		if parameters.ChildValue(1).String() == "clicked" {
+			tray.activate(parameters.ChildValue(0).Int32())
+		}
+		invocation.ReturnValue(nil)
+	case "EventGroup":
+		events := parameters.ChildValue(0)
+		for i := uint(0); i < events.NChildren(); i++ {
+			event := events.ChildValue(i)
+			if event.ChildValue(1).String() == "clicked" {
+				tray.activate(event.ChildValue(0).Int32())
+			}
+		}
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{glib.NewVariantArray(glib.NewVariantType("i"), nil)}))
+	case "AboutToShow":
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{glib.NewVariantBoolean(false)}))
+	case "AboutToShowGroup":
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{
+			glib.NewVariantArray(glib.NewVariantType("i"), nil),
+			glib.NewVariantArray(glib.NewVariantType("i"), nil),
+		}))
+	default:
+		invocation.ReturnDBusError(trayMenuInterface+".Error", "unknown method "+methodName)
+	}
+}
+
+func (tray *Tray) menuItems() []trayMenuItem {
+	var items []trayMenuItem
+
+	recentItems, err := clipboard.search("", trayRecentItemCount)
+	if err != nil {
+		log.Printf("Failed to get recent items: %v", err)
+	}
+	tray.recentItems = tray.recentItems[:0]
+	for i, item := range recentItems {
+		tray.recentItems = append(tray.recentItems, item.id)
+		items = append(items, trayMenuItem{int32(i + 1), map[string]*glib.Variant{
+			"label": glib.NewVariantString(trayLabel(item)),
+		}})
+	}
+	if len(recentItems) == 0 {
+		items = append(items, trayMenuItem{trayMenuEmptyHistory, map[string]*glib.Variant{
+			"label":   glib.NewVariantString("History is empty"),
+			"enabled": glib.NewVariantBoolean(false),
+		}})
+	}
+
+	toggleState := int32(0)
+	if clipboard.paused {
+		toggleState = 1
+	}
+	items = append(items,
+		trayMenuItem{trayMenuSeparator, map[string]*glib.Variant{"type": glib.NewVariantString("separator")}},
+		trayMenuItem{trayMenuPause, map[string]*glib.Variant{
+			"label":        glib.NewVariantString("Pause Capture"),
+			"toggle-type":  glib.NewVariantString("checkmark"),
+			"toggle-state": glib.NewVariantInt32(toggleState),
+		}},
+		trayMenuItem{trayMenuOpen, map[string]*glib.Variant{"label": glib.NewVariantString("Open Clyp")}},
+		trayMenuItem{trayMenuClear, map[string]*glib.Variant{"label": glib.NewVariantString("Clear History…")}},
+	)
+
+	return items
+}
+
+func (tray *Tray) activate(id int32) {
+	switch {
+	case id >= 1 && int(id) <= len(tray.recentItems):
+		if err := clipboard.copy(strconv.Itoa(tray.recentItems[id-1])); err != nil {
+			log.Printf("Failed to copy item: %v", err)
+		}
+	case id == trayMenuPause:
+		clipboard.setPaused(!clipboard.paused)
+	case id == trayMenuOpen:
+		openMainWindow()
+	case id == trayMenuClear:
+		tray.confirmClearHistory()
+	}
+}
+
+func (tray *Tray) confirmClearHistory() {
+	gui.showConfirmDialog("Clear History", "Move all items except pinned ones to the trash?", "Clear", func() {
+		if _, _, err := clipboard.clearHistory(); err != nil {
+			log.Printf("Failed to clear history: %v", err)
+		}
+	})
+}
+
+func (tray *Tray) update() {
+	if tray.connection == nil {
+		return
+	}
+
+	tray.revision++
+	tray.emit(trayMenuPath, trayMenuInterface, "LayoutUpdated", glib.NewVariantTuple([]*glib.Variant{glib.NewVariantUint32(tray.revision), glib.NewVariantInt32(0)}))
+	tray.emit(trayItemPath, trayItemInterface, "NewIcon", nil)
+	tray.emit(trayItemPath, trayItemInterface, "NewToolTip", nil)
+}
+
+func (tray *Tray) emit(objectPath, interfaceName, signalName string, parameters *glib.Variant) {
+	if err := tray.connection.EmitSignal("", objectPath, interfaceName, signalName, parameters); err != nil {
+		log.Printf("Failed to emit D-Bus signal: %v", err)
+	}
+}
+
+func trayLayout(id int32, properties map[string]*glib.Variant, children []*glib.Variant) *glib.Variant {
+	return glib.NewVariantTuple([]*glib.Variant{
+		glib.NewVariantInt32(id),
+		portalOptions(properties),
+		glib.NewVariantArray(glib.NewVariantType("v"), children),
+	})
+}
+
+func trayIDs(ids *glib.Variant) map[int32]bool {
+	requested := make(map[int32]bool)
+	for i := uint(0); i < ids.NChildren(); i++ {
+		requested[ids.ChildValue(i).Int32()] = true
+	}
+	return requested
+}
+
+func trayLabel(item ClipboardItem) string {
+	if item.itemType == 2 {
+		return "Image · " + item.dateTime
+	}
+	label := []rune(strings.Join(strings.Fields(item.content), " "))
+	if len(label) > trayLabelLength {
+		label = append(label[:trayLabelLength], '…')
+	}
+	return strings.ReplaceAll(string(label), "_", "__")
+}
+
+func openMainWindow() {
+	app.spawn()
+}
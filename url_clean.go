@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+type URLRule struct {
+	Domain   string   `json:"domain,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Params   []string `json:"params,omitempty"`
+	Redirect string   `json:"redirect,omitempty"`
+}
+
+const maxURLRedirects = 5
+
+var defaultURLRules = []URLRule{
+	{Params: []string{
+		"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "twclid",
+		"ttclid", "li_fat_id", "mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok", "igshid", "oly_anon_id",
+		"oly_enc_id", "vero_id", "rb_clickid", "s_cid", "_ga", "_gl", "ref_src", "ref_url",
+	}},
+	{Domain: "amazon.com", Params: []string{"ref", "ref_", "pf_rd_*", "pd_rd_*", "psc", "qid", "sr", "crid", "sprefix", "content-id"}},
+	{Domain: "youtube.com", Params: []string{"si", "feature", "pp"}},
+	{Domain: "youtu.be", Params: []string{"si", "feature"}},
+	{Domain: "twitter.com", Params: []string{"s", "t", "ref_src"}},
+	{Domain: "x.com", Params: []string{"s", "t"}},
+	{Domain: "instagram.com", Params: []string{"igsh", "igshid", "img_index"}},
+	{Domain: "linkedin.com", Params: []string{"trk", "trackingId", "lipi", "midToken", "midSig", "trkEmail", "eid"}},
+	{Domain: "reddit.com", Params: []string{"share_id", "ref", "ref_source", "rdt"}},
+	{Domain: "spotify.com", Params: []string{"si", "context"}},
+	{Domain: "google.com", Path: "/url", Redirect: "q"},
+	{Domain: "google.com", Path: "/url", Redirect: "url"},
+	{Domain: "l.facebook.com", Path: "/l.php", Redirect: "u"},
+	{Domain: "lm.facebook.com", Path: "/l.php", Redirect: "u"},
+	{Domain: "l.instagram.com", Redirect: "u"},
+	{Domain: "youtube.com", Path: "/redirect", Redirect: "q"},
+	{Domain: "safelinks.protection.outlook.com", Redirect: "url"},
+	{Domain: "slack-redir.net", Path: "/link", Redirect: "url"},
+	{Domain: "steamcommunity.com", Path: "/linkfilter/", Redirect: "url"},
+	{Domain: "out.reddit.com", Redirect: "url"},
+	{Domain: "duckduckgo.com", Path: "/l/", Redirect: "uddg"},
+}
+
+func urlRules() []URLRule {
+	return append(defaultURLRules[:len(defaultURLRules):len(defaultURLRules)], config.URLRules...)
+}
+
+func (rule URLRule) matches(parsed *url.URL) bool {
+	if rule.Domain != "" {
+		host := strings.ToLower(parsed.Hostname())
+		domain := strings.ToLower(rule.Domain)
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	return rule.Path == "" || parsed.Path == rule.Path
+}
+
+func matchesParam(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return name == pattern
+}
+
+func cleanURL(text string) string {
+	if !isURL(text) {
+		return text
+	}
+	parsed, err := url.Parse(text)
+	if err != nil || parsed.Host == "" {
+		return text
+	}
+
+	rules := urlRules()
+	changed := false
+
+	for range maxURLRedirects {
+		target := unwrapRedirect(parsed, rules)
+		if target == nil {
+			break
+		}
+		parsed = target
+		changed = true
+	}
+
+	var params []string
+	for _, param := range strings.Split(parsed.RawQuery, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(name); err == nil && isTrackingParam(parsed, name, rules) {
+			changed = true
+			continue
+		}
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+
+	if !changed {
+		return text
+	}
+	parsed.RawQuery = strings.Join(params, "&")
+	parsed.ForceQuery = false
+
+	return parsed.String()
+}
+
+func isTrackingParam(parsed *url.URL, name string, rules []URLRule) bool {
+	for _, rule := range rules {
+		if len(rule.Params) == 0 || !rule.matches(parsed) {
+			continue
+		}
+		for _, pattern := range rule.Params {
+			if matchesParam(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unwrapRedirect(parsed *url.URL, rules []URLRule) *url.URL {
+	for _, rule := range rules {
+		if rule.Redirect == "" || !rule.matches(parsed) {
+			continue
+		}
+		target, err := url.Parse(parsed.Query().Get(rule.Redirect))
+		if err != nil || !isURL(target.String()) || target.Host == "" {
+			continue
+		}
+		return target
+	}
+	return nil
+}
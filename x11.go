@@ -0,0 +1,26 @@
+package main
+
+// #cgo pkg-config: x11
+// #include <stdlib.h>
+// #include <X11/Xlib.h>
+import "C"
+
+import "unsafe"
+
+// x11SelectionOwner returns the window owning the CLIPBOARD selection, or 0
+// when it has no owner or the display can not be opened.
+func x11SelectionOwner() uint64 {
+	display := C.XOpenDisplay(nil)
+	if display == nil {
+		return 0
+	}
+	defer C.XCloseDisplay(display)
+
+	name := C.CString("CLIPBOARD")
+	defer C.free(unsafe.Pointer(name))
+	atom := C.XInternAtom(display, name, C.True)
+	if atom == 0 {
+		return 0
+	}
+	return uint64(C.XGetSelectionOwner(display, atom))
+}